@@ -3,14 +3,21 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
 	"net/http"
+	"net/netip"
+	"slices"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	pb "github.com/iedon/dn42_map_go/proto"
 )
 
+// communityTopASes is the number of top ASes listed per community in /communities
+const communityTopASes = 5
+
 // setHeaders sets HTTP headers for responses
 func setHeaders(w http.ResponseWriter, contentType string, lastModified *time.Time) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -26,12 +33,7 @@ func setHeaders(w http.ResponseWriter, contentType string, lastModified *time.Ti
 
 // findNodeByASN finds a node by ASN
 func (s *Server) findNodeByASN(asn uint32) *pb.Node {
-	for _, node := range s.graph.Nodes {
-		if node.Asn == asn {
-			return node
-		}
-	}
-	return nil
+	return s.index.Node(asn)
 }
 
 // parseASNFromURL extracts and parses ASN from URL
@@ -44,27 +46,68 @@ func (s *Server) parseASNFromURL(path string) (uint32, error) {
 	return uint32(asn), nil
 }
 
+// authorize validates the bearer token of a request
+func (s *Server) authorize(r *http.Request) bool {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" || len(authHeader) < 7 || authHeader[:7] != "Bearer " {
+		return false
+	}
+
+	token := authHeader[7:]
+	return token == s.config.API.AuthToken
+}
+
 // handleGenerate handles /generate requests
 func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request) {
 	// Validate authentication token
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" || len(authHeader) < 7 || authHeader[:7] != "Bearer " {
+	if !s.authorize(r) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	token := authHeader[7:]
-	if token != s.config.API.AuthToken {
+	// Generate map, coalescing with a running or queued job
+	started := s.jobs.Request(jobGenerate)
+
+	setHeaders(w, "text/plain", nil)
+	w.WriteHeader(http.StatusAccepted)
+	if started {
+		w.Write([]byte("Map generation requested at: " + time.Now().UTC().Format(http.TimeFormat)))
+	} else {
+		w.Write([]byte("Map generation already queued at: " + time.Now().UTC().Format(http.TimeFormat)))
+	}
+}
+
+// handleGenerateStatus handles /generate/status requests
+func (s *Server) handleGenerateStatus(w http.ResponseWriter, r *http.Request) {
+	setHeaders(w, "application/json", nil)
+	if err := json.NewEncoder(w).Encode(s.jobs.Status()); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// handleUpdate handles /update requests
+func (s *Server) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	// Validate authentication token
+	if !s.authorize(r) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Generate map
-	go s.generateMap()
+	if len(mrtUpdateSources(s.config)) == 0 {
+		http.Error(w, "No MRT update URLs configured", http.StatusNotImplemented)
+		return
+	}
+
+	// Apply updates to the base RIB, coalescing with a running or queued job
+	started := s.jobs.Request(jobUpdate)
 
-	w.WriteHeader(http.StatusAccepted)
 	setHeaders(w, "text/plain", nil)
-	w.Write([]byte("Map generation requested at: " + time.Now().UTC().Format(http.TimeFormat)))
+	w.WriteHeader(http.StatusAccepted)
+	if started {
+		w.Write([]byte("Map update requested at: " + time.Now().UTC().Format(http.TimeFormat)))
+	} else {
+		w.Write([]byte("Map update already queued at: " + time.Now().UTC().Format(http.TimeFormat)))
+	}
 }
 
 // handleMap handles /map requests
@@ -110,23 +153,276 @@ func (s *Server) handleRanking(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//  Create a copy of nodes to avoid polluting original data
-	nodes := make([]*pb.Node, len(s.graph.Nodes))
-	copy(nodes, s.graph.Nodes)
+	afName := r.URL.Query().Get("af")
+	af, ok := rankingAFs[afName]
+	if !ok {
+		http.Error(w, "Unknown address family, available: ipv4, ipv6, multicast", http.StatusBadRequest)
+		return
+	}
 
-	// Sort by Centrality.Ranking
-	sort.Slice(nodes, func(i, j int) bool {
-		return nodes[i].Centrality.Ranking < nodes[j].Centrality.Ranking
-	})
+	profile := r.URL.Query().Get("profile")
+	if profile == "" {
+		profile = defaultRankingProfile
+	}
+
+	var nodes []rankedNode
+	if profile == defaultRankingProfile {
+		// The map itself is ranked with the default profile
+		nodes = storedRanking(s.graph.Nodes, af)
+	} else {
+		weights, ok := s.profiles[profile]
+		if !ok {
+			http.Error(w, fmt.Sprintf("Unknown ranking profile, available: %s",
+				strings.Join(s.rankingProfileNames(), ", ")), http.StatusBadRequest)
+			return
+		}
+		nodes = rankNodes(s.graph.Nodes, af, weights)
+	}
+
+	// Optionally order by a single metric instead of the index
+	sortKey := r.URL.Query().Get("sort")
+	if sortKey != "" && sortKey != "index" {
+		metric, ok := rankingSortKeys[sortKey]
+		if !ok {
+			http.Error(w, fmt.Sprintf("Unknown sort key, available: index, %s",
+				strings.Join(slices.Sorted(maps.Keys(rankingSortKeys)), ", ")), http.StatusBadRequest)
+			return
+		}
+		sort.SliceStable(nodes, func(i, j int) bool {
+			return metric(nodes[i]) > metric(nodes[j])
+		})
+	}
 
 	setHeaders(w, "text/plain", &s.lastModified)
 
 	fmt.Fprintf(w, "MAP.DN42 Global Rank\n")
 	fmt.Fprintf(w, "Last update: %s\n", s.lastModified.UTC().Format(http.TimeFormat))
-	fmt.Fprintf(w, "Rank   ASN         Desc                            Index\n")
-	for _, node := range nodes {
-		fmt.Fprintf(w, "%-5d  %-10d  %-30s  %d\n",
-			node.Centrality.Ranking, node.Asn, node.Desc, node.Centrality.Index)
+	if afName != "" {
+		fmt.Fprintf(w, "Address family: %s\n", afName)
+	}
+	if profile != defaultRankingProfile {
+		fmt.Fprintf(w, "Profile: %s\n", profile)
+	}
+	if sortKey != "" && sortKey != "index" {
+		fmt.Fprintf(w, "Sorted by: %s\n", sortKey)
+	}
+	fmt.Fprintf(w, "Rank   ASN         Desc                            Index  PageRank    Eigenvector  Harmonic  Cone\n")
+	for i, node := range nodes {
+		fmt.Fprintf(w, "%-5d  %-10d  %-30s  %-5d  %-10.6f  %-11.6f  %-8.6f  %d\n",
+			i+1, node.Asn, node.Desc, node.Index,
+			node.Centrality.Pagerank, node.Centrality.Eigenvector, node.Centrality.Harmonic,
+			node.CustomerCone.GetAsns())
+	}
+}
+
+// handleResilience handles /resilience requests
+func (s *Server) handleResilience(w http.ResponseWriter, r *http.Request) {
+	s.graphMutex.RLock()
+	defer s.graphMutex.RUnlock()
+
+	if s.graph == nil {
+		http.Error(w, "Map data not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	type criticalNode struct {
+		ASN        uint32 `json:"asn"`
+		Desc       string `json:"desc"`
+		CoreNumber uint32 `json:"coreNumber"`
+		CutOff     uint32 `json:"cutOff"`
+	}
+	type criticalLink struct {
+		Source uint32 `json:"source"`
+		Target uint32 `json:"target"`
+		CutOff uint32 `json:"cutOff"`
+	}
+	response := struct {
+		LastModified       string         `json:"last_modified"`
+		ArticulationPoints []criticalNode `json:"articulationPoints"`
+		Bridges            []criticalLink `json:"bridges"`
+	}{
+		LastModified:       s.lastModified.UTC().Format(http.TimeFormat),
+		ArticulationPoints: []criticalNode{},
+		Bridges:            []criticalLink{},
+	}
+
+	for _, node := range s.graph.Nodes {
+		if node.ArticulationPoint {
+			response.ArticulationPoints = append(response.ArticulationPoints, criticalNode{
+				ASN:        node.Asn,
+				Desc:       node.Desc,
+				CoreNumber: node.CoreNumber,
+				CutOff:     node.ArticulationCutOff,
+			})
+		}
+	}
+
+	// Bridges are flagged on both directions of a link, list each AS pair once
+	seen := make(map[[2]uint32]bool)
+	for _, link := range s.graph.Links {
+		if !link.Bridge {
+			continue
+		}
+		source, target := s.graph.Nodes[link.Source].Asn, s.graph.Nodes[link.Target].Asn
+		pair := [2]uint32{min(source, target), max(source, target)}
+		if seen[pair] {
+			continue
+		}
+		seen[pair] = true
+		response.Bridges = append(response.Bridges, criticalLink{
+			Source: source,
+			Target: target,
+			CutOff: link.BridgeCutOff,
+		})
+	}
+
+	// Most critical first
+	sort.SliceStable(response.ArticulationPoints, func(i, j int) bool {
+		return response.ArticulationPoints[i].CutOff > response.ArticulationPoints[j].CutOff
+	})
+	sort.SliceStable(response.Bridges, func(i, j int) bool {
+		return response.Bridges[i].CutOff > response.Bridges[j].CutOff
+	})
+
+	setHeaders(w, "application/json", &s.lastModified)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// handleCommunities handles /communities requests
+func (s *Server) handleCommunities(w http.ResponseWriter, r *http.Request) {
+	s.graphMutex.RLock()
+	defer s.graphMutex.RUnlock()
+
+	if s.graph == nil {
+		http.Error(w, "Map data not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	type communityAS struct {
+		ASN   uint32 `json:"asn"`
+		Desc  string `json:"desc"`
+		Index uint32 `json:"index"`
+	}
+	type communityLinks struct {
+		Community uint32 `json:"community"`
+		Links     int    `json:"links"`
+	}
+	type community struct {
+		ID                  uint32           `json:"id"`
+		Size                int              `json:"size"`
+		TopASes             []communityAS    `json:"topAses"`
+		InternalLinks       int              `json:"internalLinks"`
+		InterCommunityLinks int              `json:"interCommunityLinks"`
+		Neighbors           []communityLinks `json:"neighbors"` // Links to each other community
+		degree              int
+		neighbors           map[uint32]int
+	}
+
+	communities := make([]*community, 0)
+	for _, node := range s.graph.Nodes {
+		for int(node.Community) >= len(communities) {
+			communities = append(communities, &community{
+				ID:        uint32(len(communities)),
+				TopASes:   []communityAS{},
+				Neighbors: []communityLinks{},
+				neighbors: make(map[uint32]int),
+			})
+		}
+		c := communities[node.Community]
+		c.Size++
+		c.TopASes = append(c.TopASes, communityAS{ASN: node.Asn, Desc: node.Desc, Index: node.Centrality.GetIndex()})
+	}
+
+	// Count each AS pair once, links may be present in both directions
+	seen := make(map[[2]uint32]bool)
+	links := 0
+	for _, link := range s.graph.Links {
+		source, target := s.graph.Nodes[link.Source], s.graph.Nodes[link.Target]
+		pair := [2]uint32{min(source.Asn, target.Asn), max(source.Asn, target.Asn)}
+		if source.Asn == target.Asn || seen[pair] {
+			continue
+		}
+		seen[pair] = true
+		links++
+
+		a, b := communities[source.Community], communities[target.Community]
+		a.degree++
+		b.degree++
+		if a == b {
+			a.InternalLinks++
+			continue
+		}
+		a.InterCommunityLinks++
+		b.InterCommunityLinks++
+		a.neighbors[b.ID]++
+		b.neighbors[a.ID]++
+	}
+
+	// Modularity of the partition, between -0.5 and 1
+	modularity := 0.0
+	for _, c := range communities {
+		sort.SliceStable(c.TopASes, func(i, j int) bool {
+			return c.TopASes[i].Index > c.TopASes[j].Index
+		})
+		c.TopASes = c.TopASes[:min(len(c.TopASes), communityTopASes)]
+		for _, id := range slices.Sorted(maps.Keys(c.neighbors)) {
+			c.Neighbors = append(c.Neighbors, communityLinks{Community: id, Links: c.neighbors[id]})
+		}
+		if links > 0 {
+			share := float64(c.degree) / float64(2*links)
+			modularity += float64(c.InternalLinks)/float64(links) - share*share
+		}
+	}
+
+	setHeaders(w, "application/json", &s.lastModified)
+	if err := json.NewEncoder(w).Encode(struct {
+		LastModified string       `json:"last_modified"`
+		Modularity   float64      `json:"modularity"`
+		Communities  []*community `json:"communities"`
+	}{
+		LastModified: s.lastModified.UTC().Format(http.TimeFormat),
+		Modularity:   modularity,
+		Communities:  communities,
+	}); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// handleROA handles /roa requests
+func (s *Server) handleROA(w http.ResponseWriter, r *http.Request) {
+	s.graphMutex.RLock()
+	defer s.graphMutex.RUnlock()
+
+	if s.roas == nil {
+		http.Error(w, "ROA data not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var write func() error
+	contentType := "text/plain"
+	switch r.URL.Query().Get("type") {
+	case "", "json":
+		contentType = "application/json"
+		write = func() error { return s.roas.WriteJSON(w) }
+	case "bird4":
+		write = func() error { return s.roas.WriteBird(w, false) }
+	case "bird6":
+		write = func() error { return s.roas.WriteBird(w, true) }
+	default:
+		http.Error(w, "Unknown ROA type, available: json, bird4, bird6", http.StatusBadRequest)
+		return
+	}
+
+	if !checkIfModified(r, s.roas.Generated) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	setHeaders(w, contentType, &s.roas.Generated)
+	if err := write(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
@@ -157,3 +453,54 @@ func (s *Server) handleASN(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 	}
 }
+
+// handleRoute handles /route/<address> requests, returning the most specific
+// route covering an address and the ASes originating it
+func (s *Server) handleRoute(w http.ResponseWriter, r *http.Request) {
+	s.graphMutex.RLock()
+	defer s.graphMutex.RUnlock()
+
+	if s.graph == nil {
+		http.Error(w, "Map data not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	addr, err := netip.ParseAddr(r.URL.Path[len("/route/"):])
+	if err != nil {
+		http.Error(w, "invalid address format", http.StatusBadRequest)
+		return
+	}
+	multicast, _ := strconv.ParseBool(r.URL.Query().Get("multicast"))
+
+	prefix, origins, ok := s.index.LookupRoute(addr, multicast)
+	if !ok {
+		http.Error(w, "No route found", http.StatusNotFound)
+		return
+	}
+
+	type origin struct {
+		ASN  uint32 `json:"asn"`
+		Desc string `json:"desc"`
+	}
+	response := struct {
+		Address string   `json:"address"`
+		Prefix  string   `json:"prefix"`
+		Origins []origin `json:"origins"`
+	}{
+		Address: addr.String(),
+		Prefix:  prefix.String(),
+		Origins: make([]origin, 0, len(origins)),
+	}
+	for _, asn := range origins {
+		desc := fmt.Sprintf("AS%d", asn)
+		if node := s.findNodeByASN(asn); node != nil {
+			desc = node.Desc
+		}
+		response.Origins = append(response.Origins, origin{ASN: asn, Desc: desc})
+	}
+
+	setHeaders(w, "application/json", &s.lastModified)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
@@ -2,7 +2,11 @@ package centrality
 
 import (
 	"math"
+	"runtime"
+	"slices"
 	"sort"
+	"sync"
+	"sync/atomic"
 )
 
 // Node represents a node in the graph
@@ -12,11 +16,21 @@ type Node struct {
 	InDegree       float64
 	OutDegree      float64
 	Betweenness    float64
-	InBetweenness  float64
-	OutBetweenness float64
+	InBetweenness  float64 // Directed shortest paths with the node as first hop after the source
+	OutBetweenness float64 // Directed shortest paths with the node as last hop before the target
 	Closeness      float64
+	Harmonic       float64
+	PageRank       float64
+	Eigenvector    float64
 	Index          uint32
 	Ranking        uint32
+
+	Articulation       bool   // Removing the node disconnects the graph
+	ArticulationCutOff uint32 // Nodes cut off from the largest remaining component
+	CoreNumber         uint32 // Largest k of a k-core containing the node
+	Component          uint32 // Connected component of the node on the undirected graph
+	Reachable          uint32 // Other nodes in the same component
+	Community          uint32 // Louvain community, numbered by size with 0 the largest
 }
 
 // Graph represents the entire graph
@@ -28,23 +42,33 @@ type Graph struct {
 	}
 	// Adjacency list representation
 	adjList map[uint32][]uint32
+	// Node lookup by ASN
+	nodeByASN map[uint32]*Node
+	// Weights of the dn42Index
+	weights Weights
+	// Links whose removal disconnects the graph
+	bridges []Bridge
 }
 
-// NewGraph creates a new graph
-func NewGraph() *Graph {
+// NewGraph creates a new graph ranking nodes with the given dn42Index weights
+func NewGraph(weights Weights) *Graph {
 	return &Graph{
 		Nodes: make([]*Node, 0),
 		Links: make([]struct {
 			Source uint32
 			Target uint32
 		}, 0),
-		adjList: make(map[uint32][]uint32),
+		adjList:   make(map[uint32][]uint32),
+		nodeByASN: make(map[uint32]*Node),
+		weights:   weights,
 	}
 }
 
 // AddNode adds a node
 func (g *Graph) AddNode(asn uint32) {
-	g.Nodes = append(g.Nodes, &Node{ASN: asn})
+	node := &Node{ASN: asn}
+	g.Nodes = append(g.Nodes, node)
+	g.nodeByASN[asn] = node
 	g.adjList[asn] = make([]uint32, 0)
 }
 
@@ -59,11 +83,43 @@ func (g *Graph) AddLink(source, target uint32) {
 	// Don't add the reverse edge for directed graph
 }
 
-// CalculateCentrality calculates all centrality metrics
+// CalculateCentrality calculates all centrality metrics, the resilience of
+// the graph and its communities
 func (g *Graph) CalculateCentrality() {
+	adj := g.calculateMetrics()
+	g.calculateResilience(adj)
+	g.calculateCommunities(adj)
+	Rank(g.Nodes, g.weights)
+}
+
+// CalculateMetrics calculates only the degree, betweenness, closeness and
+// spectral centrality and ranks the nodes by them, for graphs that don't
+// need articulation points, bridges, k-cores and communities
+func (g *Graph) CalculateMetrics() {
+	g.calculateMetrics()
+	Rank(g.Nodes, g.weights)
+}
+
+// calculateMetrics calculates the centrality metrics and returns the
+// undirected adjacency they were calculated on
+func (g *Graph) calculateMetrics() csr {
+	ids := g.nodeIDs()
+	adj := g.undirectedCSR(ids)
+
 	g.calculateDegree()
-	g.calculateBetweennessAndCloseness()
-	g.calculateIndex()
+	g.calculateBetweennessAndCloseness(adj, adj.outgoing())
+	g.calculatePageRank(ids)
+	g.calculateEigenvector(adj)
+	return adj
+}
+
+// nodeIDs maps each ASN to the position of its node in g.Nodes
+func (g *Graph) nodeIDs() map[uint32]int32 {
+	ids := make(map[uint32]int32, len(g.Nodes))
+	for i, node := range g.Nodes {
+		ids[node.ASN] = int32(i)
+	}
+	return ids
 }
 
 // calculateDegree calculates degree centrality
@@ -85,138 +141,280 @@ func (g *Graph) calculateDegree() {
 	}
 }
 
-// calculateBetweennessAndCloseness calculates betweenness and closeness centrality
-// based on the Brandes algorithm (similar to the Python implementation)
-func (g *Graph) calculateBetweennessAndCloseness() {
-	// Create a bidirectional graph for centrality calculations
-	// This is similar to the fullasmap in the Python code
-	bidirectionalGraph := make(map[uint32]map[uint32]struct{})
+// Directions of the links behind an undirected edge, seen from its row node
+const (
+	linkOut uint8 = 1 << iota // Row node -> neighbor
+	linkIn                    // Neighbor -> row node
+)
 
-	// Initialize the bidirectional graph
-	for _, node := range g.Nodes {
-		bidirectionalGraph[node.ASN] = make(map[uint32]struct{})
-	}
+// csr is an undirected adjacency in compressed sparse row form. The
+// neighbors of node i are targets[offsets[i]:offsets[i+1]], dirs holds the
+// directions of the links behind each edge.
+type csr struct {
+	offsets []int32
+	targets []int32
+	dirs    []uint8
+}
+
+// csrEntry is an edge of a node while the adjacency is being built
+type csrEntry struct {
+	target int32
+	dir    uint8
+}
 
-	// Fill the bidirectional graph
+// undirectedCSR builds the undirected adjacency of the graph over the node
+// IDs, without duplicate edges or self-loops
+func (g *Graph) undirectedCSR(ids map[uint32]int32) csr {
+	n := len(g.Nodes)
+	neighbors := make([][]csrEntry, n)
 	for _, link := range g.Links {
-		bidirectionalGraph[link.Source][link.Target] = struct{}{}
-		bidirectionalGraph[link.Target][link.Source] = struct{}{}
+		src, okSrc := ids[link.Source]
+		dst, okDst := ids[link.Target]
+		if !okSrc || !okDst || src == dst {
+			continue
+		}
+		neighbors[src] = append(neighbors[src], csrEntry{dst, linkOut})
+		neighbors[dst] = append(neighbors[dst], csrEntry{src, linkIn})
 	}
 
-	// Initialize betweenness values
-	betweenness := make(map[uint32]float64)
-	for _, node := range g.Nodes {
-		betweenness[node.ASN] = 0.0
+	adj := csr{offsets: make([]int32, n+1)}
+	for i, list := range neighbors {
+		slices.SortFunc(list, func(a, b csrEntry) int {
+			return int(a.target) - int(b.target)
+		})
+		for j, entry := range list {
+			last := len(adj.targets) - 1
+			if j > 0 && adj.targets[last] == entry.target {
+				adj.dirs[last] |= entry.dir
+				continue
+			}
+			adj.targets = append(adj.targets, entry.target)
+			adj.dirs = append(adj.dirs, entry.dir)
+		}
+		adj.offsets[i+1] = int32(len(adj.targets))
 	}
+	return adj
+}
 
-	// Initialize closeness values and all distances
-	allDistances := make(map[uint32]map[uint32]float64)
-
-	// For each node as a source
-	for _, sourceNode := range g.Nodes {
-		source := sourceNode.ASN
-
-		// Initialize distances for this source
-		distances := make(map[uint32]float64)
-		allDistances[source] = distances
-
-		// Initialize data structures for Brandes algorithm
-		pathVia := make(map[uint32][]uint32)
-		sigma := make(map[uint32]int)
-		delta := make(map[uint32]float64)
-		searchOrder := make([]uint32, 0)
-
-		// Initialize for all nodes
-		for _, node := range g.Nodes {
-			asn := node.ASN
-			distances[asn] = math.Inf(1)
-			pathVia[asn] = make([]uint32, 0)
-			sigma[asn] = 0
-			delta[asn] = 0.0
-		}
+// neighbors returns the neighbors of node i
+func (adj csr) neighbors(i int32) []int32 {
+	return adj.targets[adj.offsets[i]:adj.offsets[i+1]]
+}
 
-		// BFS initialization
-		distances[source] = 0
-		sigma[source] = 1
-		queue := []uint32{source}
-
-		// BFS to find shortest paths
-		for len(queue) > 0 {
-			// Dequeue
-			current := queue[0]
-			queue = queue[1:]
-			searchOrder = append(searchOrder, current)
-
-			// For each neighbor
-			for neighbor := range bidirectionalGraph[current] {
-				// If this is the first time we see this node
-				if math.IsInf(distances[neighbor], 1) {
-					distances[neighbor] = distances[current] + 1
-					queue = append(queue, neighbor)
-				}
-
-				// If this is a shortest path to neighbor
-				if distances[neighbor] == distances[current]+1 {
-					sigma[neighbor] += sigma[current]
-					pathVia[neighbor] = append(pathVia[neighbor], current)
-				}
+// outgoing returns the directed adjacency of the links as added by AddLink,
+// keeping only the edges with a link from the row node to its neighbor
+func (adj csr) outgoing() csr {
+	out := csr{offsets: make([]int32, len(adj.offsets))}
+	for i := range len(adj.offsets) - 1 {
+		for j := adj.offsets[i]; j < adj.offsets[i+1]; j++ {
+			if adj.dirs[j]&linkOut != 0 {
+				out.targets = append(out.targets, adj.targets[j])
+				out.dirs = append(out.dirs, linkOut)
 			}
 		}
+		out.offsets[i+1] = int32(len(out.targets))
+	}
+	return out
+}
 
-		// Backward pass to accumulate betweenness
-		for i := len(searchOrder) - 1; i >= 0; i-- {
-			current := searchOrder[i]
-			coeff := (1.0 + delta[current]) / float64(sigma[current])
+// brandesWorker holds the per-source buffers and the betweenness
+// accumulators of one worker
+type brandesWorker struct {
+	dist           []int32
+	sigma          []float64
+	delta          []float64
+	order          []int32
+	betweenness    []float64
+	inBetweenness  []float64
+	outBetweenness []float64
+}
 
-			for _, upstream := range pathVia[current] {
-				delta[upstream] += float64(sigma[upstream]) * coeff
-			}
+func newBrandesWorker(n int) *brandesWorker {
+	return &brandesWorker{
+		dist:           make([]int32, n),
+		sigma:          make([]float64, n),
+		delta:          make([]float64, n),
+		order:          make([]int32, 0, n),
+		betweenness:    make([]float64, n),
+		inBetweenness:  make([]float64, n),
+		outBetweenness: make([]float64, n),
+	}
+}
+
+// search runs the BFS of a Brandes pass from source, counting the shortest
+// paths to every node. order lists the reached nodes by distance.
+func (w *brandesWorker) search(adj csr, source int32) {
+	for i := range w.dist {
+		w.dist[i] = -1
+		w.sigma[i] = 0
+		w.delta[i] = 0
+	}
 
-			if current != source {
-				betweenness[current] += delta[current]
+	// order doubles as the queue
+	w.dist[source] = 0
+	w.sigma[source] = 1
+	w.order = append(w.order[:0], source)
+	for head := 0; head < len(w.order); head++ {
+		current := w.order[head]
+		for _, neighbor := range adj.neighbors(current) {
+			// If this is the first time we see this node
+			if w.dist[neighbor] < 0 {
+				w.dist[neighbor] = w.dist[current] + 1
+				w.order = append(w.order, neighbor)
+			}
+			// If this is a shortest path to neighbor
+			if w.dist[neighbor] == w.dist[current]+1 {
+				w.sigma[neighbor] += w.sigma[current]
 			}
 		}
 	}
+}
 
-	// Scale betweenness values
-	n := float64(len(g.Nodes))
-	scale := 1.0 / ((n - 1) * (n - 2))
+// visit runs the Brandes single-source pass from source on the undirected
+// graph, adds the dependencies to the worker's betweenness and returns the
+// closeness and harmonic closeness of source
+func (w *brandesWorker) visit(adj csr, source int32) (float64, float64) {
+	w.search(adj, source)
+
+	// Backward pass to accumulate betweenness, predecessors are the
+	// neighbors one hop closer to the source
+	for i := len(w.order) - 1; i > 0; i-- {
+		current := w.order[i]
+		coeff := (1.0 + w.delta[current]) / w.sigma[current]
+		for _, upstream := range adj.neighbors(current) {
+			if w.dist[upstream] == w.dist[current]-1 {
+				w.delta[upstream] += w.sigma[upstream] * coeff
+			}
+		}
+		w.betweenness[current] += w.delta[current]
+	}
 
-	// Calculate closeness centrality
-	closeness := make(map[uint32]float64)
-	for source, distances := range allDistances {
-		sum := 0.0
-		count := 0
+	// Closeness over the nodes reachable from source, harmonic closeness
+	// over all other nodes with unreachable ones contributing 0
+	sum := 0
+	harmonic := 0.0
+	for _, node := range w.order[1:] {
+		sum += int(w.dist[node])
+		harmonic += 1.0 / float64(w.dist[node])
+	}
+	if sum == 0 {
+		return 0.0, 0.0
+	}
+	n := len(w.dist)
+	return float64(len(w.order)-1) / float64(sum), harmonic / float64(n-1)
+}
 
-		for target, distance := range distances {
-			if source != target && !math.IsInf(distance, 1) {
-				sum += distance
-				count++
+// visitDirected runs a Brandes pass from source following the link
+// directions and adds the proximal betweenness of the nodes: the share of
+// the paths from source with a node as their first hop to its in-betweenness,
+// and the share of the paths to each target with a node as their last hop
+// to its out-betweenness. Paths of a single link have no inner node and
+// count for neither.
+func (w *brandesWorker) visitDirected(out csr, source int32) {
+	w.search(out, source)
+
+	// Backward pass over the successors, the nodes one hop farther away
+	for i := len(w.order) - 1; i > 0; i-- {
+		current := w.order[i]
+		for _, downstream := range out.neighbors(current) {
+			if w.dist[downstream] == w.dist[current]+1 {
+				share := w.sigma[current] / w.sigma[downstream]
+				w.delta[current] += share * (1.0 + w.delta[downstream])
+				w.outBetweenness[current] += share
 			}
 		}
+		if w.dist[current] == 1 {
+			w.inBetweenness[current] += w.delta[current]
+		}
+	}
+}
+
+// calculateBetweennessAndCloseness calculates betweenness, closeness and harmonic
+// closeness centrality on the undirected graph, based on the Brandes algorithm.
+// A second pass per source on the directed adjacency out calculates the
+// proximal in- and out-betweenness: InBetweenness counts the directed
+// shortest paths on which a node is the first hop after the source, i.e. ASes
+// routing through it, OutBetweenness those on which it is the last hop
+// before the target, i.e. it carries the path into its downstream. Source
+// nodes are spread across GOMAXPROCS workers whose accumulators are merged
+// at the end.
+func (g *Graph) calculateBetweennessAndCloseness(adj, out csr) {
+	n := len(g.Nodes)
+	if n == 0 {
+		return
+	}
 
-		if count > 0 {
-			closeness[source] = float64(count) / sum
-		} else {
-			closeness[source] = 0.0
+	workers := make([]*brandesWorker, min(runtime.GOMAXPROCS(0), n))
+	closeness := make([]float64, n)
+	harmonic := make([]float64, n)
+	var next atomic.Int32
+	var wg sync.WaitGroup
+	for i := range workers {
+		w := newBrandesWorker(n)
+		workers[i] = w
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for source := next.Add(1) - 1; int(source) < n; source = next.Add(1) - 1 {
+				closeness[source], harmonic[source] = w.visit(adj, source)
+				w.visitDirected(out, source)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Merge and scale betweenness values
+	scale := 0.0
+	if n > 2 {
+		scale = 1.0 / (float64(n-1) * float64(n-2))
+	}
+	for i, node := range g.Nodes {
+		betweenness, inBetweenness, outBetweenness := 0.0, 0.0, 0.0
+		for _, w := range workers {
+			betweenness += w.betweenness[i]
+			inBetweenness += w.inBetweenness[i]
+			outBetweenness += w.outBetweenness[i]
 		}
+		node.Betweenness = betweenness * scale
+		node.InBetweenness = inBetweenness * scale
+		node.OutBetweenness = outBetweenness * scale
+		node.Closeness = closeness[i]
+		node.Harmonic = harmonic[i]
 	}
+}
 
-	// Update node values
-	for _, node := range g.Nodes {
-		node.Betweenness = betweenness[node.ASN] * scale
-		node.Closeness = closeness[node.ASN]
+// Weights are the weights of the normalized metrics in the dn42Index
+type Weights struct {
+	Betweenness float64 `json:"betweenness"` // alpha, priority on bridging roles
+	Closeness   float64 `json:"closeness"`   // beta, priority on overall connectedness
+	Degree      float64 `json:"degree"`      // gamma, priority on local influence
+}
+
+// DefaultWeights are the weights of the default dn42Index
+var DefaultWeights = Weights{Betweenness: 0.5, Closeness: 0.3, Degree: 0.2}
+
+// IsZero reports whether no weight is set
+func (w Weights) IsZero() bool {
+	return w == Weights{}
+}
+
+// normalize scales v by the maximum, or returns 0 if all values are 0
+func normalize(v, maxValue float64) float64 {
+	if maxValue <= 0 {
+		return 0
 	}
+	return v / maxValue
 }
 
-// calculateIndex calculates the dn42Index
-func (g *Graph) calculateIndex() {
+// Rank calculates the dn42Index of the given nodes from their raw metrics
+// with the given weights, sorts them by index and assigns rankings
+func Rank(nodes []*Node, weights Weights) {
 	// Find the maximum value for normalization
 	maxDegree := 0.0
 	maxBetweenness := 0.0
 	maxCloseness := 0.0
 
-	for _, node := range g.Nodes {
+	for _, node := range nodes {
 		if node.Degree > maxDegree {
 			maxDegree = node.Degree
 		}
@@ -229,40 +427,25 @@ func (g *Graph) calculateIndex() {
 	}
 
 	// Calculate the dn42Index for each node
-	const (
-		indexAlpha = 0.5
-		indexBeta  = 0.3
-		indexGamma = 0.2
-	)
-
-	for _, node := range g.Nodes {
-		normDegree := node.Degree / maxDegree
-		normBetweenness := node.Betweenness / maxBetweenness
-		normCloseness := node.Closeness / maxCloseness
-
-		index := (indexAlpha * normBetweenness) +
-			(indexBeta * normCloseness) +
-			(indexGamma * normDegree)
+	for _, node := range nodes {
+		index := (weights.Betweenness * normalize(node.Betweenness, maxBetweenness)) +
+			(weights.Closeness * normalize(node.Closeness, maxCloseness)) +
+			(weights.Degree * normalize(node.Degree, maxDegree))
 
 		node.Index = uint32(math.Round(index * 10000))
 	}
 
-	// Sort by Index and assign rankings
-	sort.Slice(g.Nodes, func(i, j int) bool {
-		return g.Nodes[i].Index > g.Nodes[j].Index
+	// Sort by Index and assign rankings, ties keep their ASN order
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Index > nodes[j].Index
 	})
 
-	for i, node := range g.Nodes {
+	for i, node := range nodes {
 		node.Ranking = uint32(i + 1)
 	}
 }
 
 // GetNode gets the node for a given ASN
 func (g *Graph) GetNode(asn uint32) *Node {
-	for _, node := range g.Nodes {
-		if node.ASN == asn {
-			return node
-		}
-	}
-	return nil
+	return g.nodeByASN[asn]
 }
@@ -0,0 +1,133 @@
+package centrality
+
+import (
+	"slices"
+	"testing"
+)
+
+// testGraph builds a graph of the given links with the default weights
+func testGraph(links ...[2]uint32) *Graph {
+	g := NewGraph(DefaultWeights)
+	var asns []uint32
+	for _, link := range links {
+		asns = append(asns, link[0], link[1])
+	}
+	slices.Sort(asns)
+	for _, asn := range slices.Compact(asns) {
+		g.AddNode(asn)
+	}
+	for _, link := range links {
+		g.AddLink(link[0], link[1])
+	}
+	return g
+}
+
+func TestResilience(t *testing.T) {
+	// Two triangles joined by the bridge 3-4, a pendant 7 on 6 and a
+	// separate component 8-9
+	g := testGraph(
+		[2]uint32{1, 2}, [2]uint32{2, 3}, [2]uint32{3, 1},
+		[2]uint32{3, 4},
+		[2]uint32{4, 5}, [2]uint32{5, 6}, [2]uint32{6, 4},
+		[2]uint32{6, 7},
+		[2]uint32{8, 9},
+	)
+	g.CalculateCentrality()
+
+	tests := []struct {
+		asn          uint32
+		articulation bool
+		cutOff       uint32
+		core         uint32
+		reachable    uint32
+	}{
+		{1, false, 0, 2, 6},
+		{2, false, 0, 2, 6},
+		{3, true, 2, 2, 6},
+		{4, true, 3, 2, 6},
+		{5, false, 0, 2, 6},
+		{6, true, 1, 2, 6},
+		{7, false, 0, 1, 6},
+		{8, false, 0, 1, 1},
+		{9, false, 0, 1, 1},
+	}
+	for _, tt := range tests {
+		node := g.GetNode(tt.asn)
+		if node.Articulation != tt.articulation || node.ArticulationCutOff != tt.cutOff ||
+			node.CoreNumber != tt.core || node.Reachable != tt.reachable {
+			t.Errorf("AS%d: articulation %v cut off %d core %d reachable %d, want %v %d %d %d", tt.asn,
+				node.Articulation, node.ArticulationCutOff, node.CoreNumber, node.Reachable,
+				tt.articulation, tt.cutOff, tt.core, tt.reachable)
+		}
+	}
+	if g.GetNode(1).Component != g.GetNode(7).Component || g.GetNode(1).Component == g.GetNode(8).Component {
+		t.Errorf("components of AS1, AS7 and AS8 = %d %d %d", g.GetNode(1).Component, g.GetNode(7).Component, g.GetNode(8).Component)
+	}
+
+	// Bridges are reported from the DFS parent, compare them undirected
+	var bridges []Bridge
+	for _, b := range g.Bridges() {
+		bridges = append(bridges, Bridge{Source: min(b.Source, b.Target), Target: max(b.Source, b.Target), CutOff: b.CutOff})
+	}
+	slices.SortFunc(bridges, func(a, b Bridge) int { return int(a.Source) - int(b.Source) })
+	want := []Bridge{{3, 4, 3}, {6, 7, 1}, {8, 9, 1}}
+	if !slices.Equal(bridges, want) {
+		t.Errorf("Bridges() = %v, want %v", bridges, want)
+	}
+}
+
+func TestCommunities(t *testing.T) {
+	// Two cliques of four joined by a single link, and a larger clique of five
+	var links [][2]uint32
+	for _, clique := range [][]uint32{{1, 2, 3, 4}, {5, 6, 7, 8}, {10, 11, 12, 13, 14}} {
+		for i, a := range clique {
+			for _, b := range clique[i+1:] {
+				links = append(links, [2]uint32{a, b})
+			}
+		}
+	}
+	g := testGraph(append(links, [2]uint32{4, 5})...)
+	g.CalculateCentrality()
+
+	want := map[uint32]uint32{
+		1: 1, 2: 1, 3: 1, 4: 1,
+		5: 2, 6: 2, 7: 2, 8: 2,
+		10: 0, 11: 0, 12: 0, 13: 0, 14: 0,
+	}
+	for asn, community := range want {
+		if got := g.GetNode(asn).Community; got != community {
+			t.Errorf("community of AS%d = %d, want %d", asn, got, community)
+		}
+	}
+}
+
+func TestBetweenness(t *testing.T) {
+	// Directed path 1 -> 2 -> 3 -> 4 with a link back from 4 to 2
+	g := testGraph([2]uint32{1, 2}, [2]uint32{2, 3}, [2]uint32{3, 4}, [2]uint32{4, 2})
+	g.CalculateMetrics()
+
+	tests := []struct {
+		asn       uint32
+		central   bool // Whether shortest paths pass through the node
+		incoming  bool // Whether the node is the first hop of directed paths
+		outgoing  bool // Whether the node is the last hop of directed paths
+		inDegree  bool
+		outDegree bool
+	}{
+		{asn: 1, outDegree: true},
+		{asn: 2, central: true, incoming: true, outgoing: true, inDegree: true, outDegree: true},
+		{asn: 3, incoming: true, outgoing: true, inDegree: true, outDegree: true},
+		{asn: 4, incoming: true, outgoing: true, inDegree: true, outDegree: true},
+	}
+	for _, tt := range tests {
+		node := g.GetNode(tt.asn)
+		if (node.Betweenness > 0) != tt.central || (node.InBetweenness > 0) != tt.incoming ||
+			(node.OutBetweenness > 0) != tt.outgoing || (node.InDegree > 0) != tt.inDegree || (node.OutDegree > 0) != tt.outDegree {
+			t.Errorf("AS%d: betweenness %v in %v out %v degree in %v out %v", tt.asn,
+				node.Betweenness, node.InBetweenness, node.OutBetweenness, node.InDegree, node.OutDegree)
+		}
+	}
+	if g.GetNode(2).Ranking != 1 {
+		t.Errorf("ranking of AS2 = %d, want 1", g.GetNode(2).Ranking)
+	}
+}
@@ -0,0 +1,194 @@
+package centrality
+
+import "slices"
+
+// Louvain parameters
+const (
+	louvainMaxPasses = 100   // Maximum local moving passes per level
+	louvainMinGain   = 1e-12 // Minimum modularity gain of a move, avoids moves on rounding noise
+)
+
+// weightedGraph is the weighted undirected graph of one Louvain level, its
+// nodes are the communities found on the previous level
+type weightedGraph struct {
+	offsets []int32
+	targets []int32
+	weights []float64
+	self    []float64 // Self-loop weight of each node, i.e. the links within the community
+}
+
+// degree returns the weighted degree of node i, self-loops count twice
+func (wg *weightedGraph) degree(i int32) float64 {
+	degree := 2 * wg.self[i]
+	for _, w := range wg.weights[wg.offsets[i]:wg.offsets[i+1]] {
+		degree += w
+	}
+	return degree
+}
+
+// calculateCommunities detects communities on the undirected graph with the
+// Louvain method, greedily moving nodes between communities while the
+// modularity increases and then merging each community into a single node,
+// until no move improves the modularity. Communities are numbered by size,
+// 0 is the largest.
+func (g *Graph) calculateCommunities(adj csr) {
+	n := len(g.Nodes)
+	level := &weightedGraph{
+		offsets: adj.offsets,
+		targets: adj.targets,
+		weights: make([]float64, len(adj.targets)),
+		self:    make([]float64, n),
+	}
+	for i := range level.weights {
+		level.weights[i] = 1
+	}
+	totalDegree := float64(len(adj.targets))
+
+	// Community of every node on the current level
+	membership := make([]int32, n)
+	for i := range membership {
+		membership[i] = int32(i)
+	}
+	for totalDegree > 0 {
+		communities, count := level.moveNodes(totalDegree)
+		if count == len(level.self) {
+			break
+		}
+		for i, c := range membership {
+			membership[i] = communities[c]
+		}
+		level = level.aggregate(communities, count)
+	}
+
+	// Number communities by size, ties in node order
+	sizes := make(map[int32]int)
+	first := make(map[int32]int)
+	var order []int32
+	for i, c := range membership {
+		if sizes[c] == 0 {
+			first[c] = i
+			order = append(order, c)
+		}
+		sizes[c]++
+	}
+	slices.SortStableFunc(order, func(a, b int32) int {
+		return sizes[b] - sizes[a]
+	})
+	ids := make(map[int32]uint32, len(order))
+	for id, c := range order {
+		ids[c] = uint32(id)
+	}
+	for i, node := range g.Nodes {
+		node.Community = ids[membership[i]]
+	}
+}
+
+// moveNodes runs the local moving phase of a Louvain level and returns the
+// community of every node, numbered from 0, and the number of communities
+func (wg *weightedGraph) moveNodes(totalDegree float64) ([]int32, int) {
+	n := int32(len(wg.self))
+	communities := make([]int32, n)
+	degree := make([]float64, n)
+	total := make([]float64, n) // Degree sum of each community
+	for i := range n {
+		communities[i] = i
+		degree[i] = wg.degree(i)
+		total[i] = degree[i]
+	}
+
+	linkWeight := make([]float64, n) // Weight from the current node to each community
+	var touched []int32
+	for range louvainMaxPasses {
+		moved := false
+		for i := range n {
+			current := communities[i]
+			touched = touched[:0]
+			for j := wg.offsets[i]; j < wg.offsets[i+1]; j++ {
+				c := communities[wg.targets[j]]
+				if linkWeight[c] == 0 {
+					touched = append(touched, c)
+				}
+				linkWeight[c] += wg.weights[j]
+			}
+
+			// Take the node out of its community and put it into the one
+			// with the highest modularity gain
+			total[current] -= degree[i]
+			best := current
+			bestGain := linkWeight[current] - total[current]*degree[i]/totalDegree
+			for _, c := range touched {
+				if gain := linkWeight[c] - total[c]*degree[i]/totalDegree; gain > bestGain+louvainMinGain {
+					best, bestGain = c, gain
+				}
+			}
+			total[best] += degree[i]
+			communities[i] = best
+			if best != current {
+				moved = true
+			}
+
+			for _, c := range touched {
+				linkWeight[c] = 0
+			}
+		}
+		if !moved {
+			break
+		}
+	}
+
+	// Renumber the remaining communities
+	ids := make([]int32, n)
+	for i := range ids {
+		ids[i] = -1
+	}
+	count := int32(0)
+	for i, c := range communities {
+		if ids[c] < 0 {
+			ids[c] = count
+			count++
+		}
+		communities[i] = ids[c]
+	}
+	return communities, int(count)
+}
+
+// aggregate builds the next Louvain level with one node per community
+func (wg *weightedGraph) aggregate(communities []int32, count int) *weightedGraph {
+	next := &weightedGraph{
+		offsets: make([]int32, count+1),
+		self:    make([]float64, count),
+	}
+	members := make([][]int32, count)
+	for i, c := range communities {
+		members[c] = append(members[c], int32(i))
+		next.self[c] += wg.self[i]
+	}
+
+	linkWeight := make([]float64, count)
+	var touched []int32
+	for c := range int32(count) {
+		touched = touched[:0]
+		for _, i := range members[c] {
+			for j := wg.offsets[i]; j < wg.offsets[i+1]; j++ {
+				d := communities[wg.targets[j]]
+				if d == c {
+					// Every internal edge is seen from both of its ends
+					next.self[c] += wg.weights[j] / 2
+					continue
+				}
+				if linkWeight[d] == 0 {
+					touched = append(touched, d)
+				}
+				linkWeight[d] += wg.weights[j]
+			}
+		}
+		slices.Sort(touched)
+		for _, d := range touched {
+			next.targets = append(next.targets, d)
+			next.weights = append(next.weights, linkWeight[d])
+			linkWeight[d] = 0
+		}
+		next.offsets[c+1] = int32(len(next.targets))
+	}
+	return next
+}
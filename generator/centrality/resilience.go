@@ -0,0 +1,173 @@
+package centrality
+
+// Bridge is a link whose removal disconnects the graph
+type Bridge struct {
+	Source uint32
+	Target uint32
+	CutOff uint32 // Nodes in the smaller component left after removing the link
+}
+
+// Bridges returns the bridges found by CalculateCentrality
+func (g *Graph) Bridges() []Bridge {
+	return g.bridges
+}
+
+// calculateResilience finds articulation points and bridges with Tarjan's
+// algorithm and calculates the k-core number of every node, all on the
+// undirected graph
+func (g *Graph) calculateResilience(adj csr) {
+	n := len(g.Nodes)
+	disc := make([]int32, n) // DFS discovery time, 0 if not visited yet
+	low := make([]int32, n)
+	parent := make([]int32, n)
+	next := make([]int32, n)   // Next neighbor to explore
+	size := make([]int32, n)   // Size of the DFS subtree
+	sepSum := make([]int32, n) // Nodes in the child subtrees removing the node would separate
+	sepMax := make([]int32, n) // Largest of those child subtrees
+	sepCount := make([]int32, n)
+
+	g.bridges = g.bridges[:0]
+	var time int32
+	var stack, component []int32
+	var bridgeChildren []int32
+	var components uint32
+
+	for root := range int32(n) {
+		if disc[root] != 0 {
+			continue
+		}
+
+		time++
+		disc[root], low[root], size[root] = time, time, 1
+		parent[root] = -1
+		next[root] = adj.offsets[root]
+		stack = append(stack[:0], root)
+		component = append(component[:0], root)
+		bridgeChildren = bridgeChildren[:0]
+
+		for len(stack) > 0 {
+			v := stack[len(stack)-1]
+			if next[v] < adj.offsets[v+1] {
+				w := adj.targets[next[v]]
+				next[v]++
+				if disc[w] == 0 {
+					time++
+					disc[w], low[w], size[w] = time, time, 1
+					parent[w] = v
+					next[w] = adj.offsets[w]
+					stack = append(stack, w)
+					component = append(component, w)
+				} else if w != parent[v] {
+					low[v] = min(low[v], disc[w])
+				}
+				continue
+			}
+
+			// All neighbors explored, report back to the parent
+			stack = stack[:len(stack)-1]
+			p := parent[v]
+			if p < 0 {
+				continue
+			}
+			low[p] = min(low[p], low[v])
+			size[p] += size[v]
+			if low[v] >= disc[p] {
+				sepSum[p] += size[v]
+				sepMax[p] = max(sepMax[p], size[v])
+				sepCount[p]++
+			}
+			if low[v] > disc[p] {
+				bridgeChildren = append(bridgeChildren, v)
+			}
+		}
+
+		// Removing a node leaves the separated subtrees and the rest of the
+		// component, everything but the largest piece is cut off
+		componentSize := size[root]
+		for _, v := range component {
+			g.Nodes[v].Component = components
+			g.Nodes[v].Reachable = uint32(componentSize - 1)
+
+			isArticulation := sepCount[v] >= 1
+			if v == root {
+				isArticulation = sepCount[v] >= 2
+			}
+			if !isArticulation {
+				continue
+			}
+			rest := componentSize - 1 - sepSum[v]
+			largest := max(sepMax[v], rest)
+			node := g.Nodes[v]
+			node.Articulation = true
+			node.ArticulationCutOff = uint32(componentSize - 1 - largest)
+		}
+
+		for _, child := range bridgeChildren {
+			g.bridges = append(g.bridges, Bridge{
+				Source: g.Nodes[parent[child]].ASN,
+				Target: g.Nodes[child].ASN,
+				CutOff: uint32(min(size[child], componentSize-size[child])),
+			})
+		}
+		components++
+	}
+
+	g.calculateCoreNumbers(adj)
+}
+
+// calculateCoreNumbers calculates the k-core number of every node with the
+// Batagelj-Zaversnik algorithm
+func (g *Graph) calculateCoreNumbers(adj csr) {
+	n := int32(len(g.Nodes))
+	deg := make([]int32, n)
+	maxDeg := int32(0)
+	for v := range n {
+		deg[v] = adj.offsets[v+1] - adj.offsets[v]
+		maxDeg = max(maxDeg, deg[v])
+	}
+
+	// Bucket sort nodes by degree
+	bin := make([]int32, maxDeg+1)
+	for _, d := range deg {
+		bin[d]++
+	}
+	start := int32(0)
+	for d := range bin {
+		count := bin[d]
+		bin[d] = start
+		start += count
+	}
+	pos := make([]int32, n)
+	vert := make([]int32, n)
+	for v := range n {
+		pos[v] = bin[deg[v]]
+		vert[pos[v]] = v
+		bin[deg[v]]++
+	}
+	for d := maxDeg; d > 0; d-- {
+		bin[d] = bin[d-1]
+	}
+	bin[0] = 0
+
+	// Peel nodes in order of their current degree
+	for i := range n {
+		v := vert[i]
+		for _, u := range adj.neighbors(v) {
+			if deg[u] > deg[v] {
+				du, pu := deg[u], pos[u]
+				pw := bin[du]
+				w := vert[pw]
+				if u != w {
+					pos[u], pos[w] = pw, pu
+					vert[pu], vert[pw] = w, u
+				}
+				bin[du]++
+				deg[u]--
+			}
+		}
+	}
+
+	for v, node := range g.Nodes {
+		node.CoreNumber = uint32(deg[v])
+	}
+}
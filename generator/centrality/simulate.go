@@ -0,0 +1,46 @@
+package centrality
+
+// Without returns a copy of the graph without the given ASes and links, for
+// what-if simulations. Links are removed in both directions. The metrics of
+// the copy are unset until CalculateCentrality is called.
+func (g *Graph) Without(asns []uint32, links [][2]uint32) *Graph {
+	removedNodes := make(map[uint32]bool, len(asns))
+	for _, asn := range asns {
+		removedNodes[asn] = true
+	}
+	removedLinks := make(map[[2]uint32]bool, 2*len(links))
+	for _, link := range links {
+		removedLinks[link] = true
+		removedLinks[[2]uint32{link[1], link[0]}] = true
+	}
+
+	sim := NewGraph(g.weights)
+	for _, node := range g.Nodes {
+		if !removedNodes[node.ASN] {
+			sim.AddNode(node.ASN)
+		}
+	}
+	for _, link := range g.Links {
+		if removedNodes[link.Source] || removedNodes[link.Target] || removedLinks[[2]uint32{link.Source, link.Target}] {
+			continue
+		}
+		sim.AddLink(link.Source, link.Target)
+	}
+	return sim
+}
+
+// AveragePathLength returns the average shortest path length between all
+// pairs of nodes that can reach each other, derived from their closeness
+func (g *Graph) AveragePathLength() float64 {
+	pairs, distances := 0.0, 0.0
+	for _, node := range g.Nodes {
+		if node.Closeness > 0 {
+			pairs += float64(node.Reachable)
+			distances += float64(node.Reachable) / node.Closeness
+		}
+	}
+	if pairs == 0 {
+		return 0
+	}
+	return distances / pairs
+}
@@ -0,0 +1,115 @@
+package centrality
+
+import "math"
+
+const (
+	pageRankDamping   = 0.85
+	spectralTolerance = 1e-10 // L1 change below which the power iteration stops
+	spectralMaxRounds = 200
+)
+
+// calculatePageRank calculates PageRank on the directed adjacency. Nodes
+// without outgoing links spread their rank evenly over all nodes.
+func (g *Graph) calculatePageRank(ids map[uint32]int32) {
+	n := len(g.Nodes)
+	if n == 0 {
+		return
+	}
+
+	// Directed adjacency over node IDs, without duplicate edges
+	out := make([][]int32, n)
+	for i, node := range g.Nodes {
+		seen := make(map[uint32]struct{}, len(g.adjList[node.ASN]))
+		for _, target := range g.adjList[node.ASN] {
+			if _, ok := seen[target]; ok {
+				continue
+			}
+			if id, ok := ids[target]; ok {
+				seen[target] = struct{}{}
+				out[i] = append(out[i], id)
+			}
+		}
+	}
+
+	rank := make([]float64, n)
+	next := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1.0 / float64(n)
+	}
+
+	for round := 0; round < spectralMaxRounds; round++ {
+		dangling := 0.0
+		for i := range next {
+			next[i] = 0
+			if len(out[i]) == 0 {
+				dangling += rank[i]
+			}
+		}
+		for i, targets := range out {
+			share := rank[i] / float64(len(targets))
+			for _, target := range targets {
+				next[target] += share
+			}
+		}
+
+		base := (1-pageRankDamping)/float64(n) + pageRankDamping*dangling/float64(n)
+		diff := 0.0
+		for i := range next {
+			next[i] = base + pageRankDamping*next[i]
+			diff += math.Abs(next[i] - rank[i])
+		}
+		rank, next = next, rank
+		if diff < spectralTolerance {
+			break
+		}
+	}
+
+	for i, node := range g.Nodes {
+		node.PageRank = rank[i]
+	}
+}
+
+// calculateEigenvector calculates eigenvector centrality on the undirected
+// graph by power iteration on A+I, which has the same leading eigenvector as
+// A but also converges on bipartite graphs. The result has unit L2 norm.
+func (g *Graph) calculateEigenvector(adj csr) {
+	n := len(g.Nodes)
+	if n == 0 {
+		return
+	}
+
+	x := make([]float64, n)
+	next := make([]float64, n)
+	for i := range x {
+		x[i] = 1.0 / float64(n)
+	}
+
+	for round := 0; round < spectralMaxRounds; round++ {
+		norm := 0.0
+		for i := range next {
+			next[i] = x[i]
+			for _, neighbor := range adj.neighbors(int32(i)) {
+				next[i] += x[neighbor]
+			}
+			norm += next[i] * next[i]
+		}
+		norm = math.Sqrt(norm)
+		if norm == 0 {
+			break
+		}
+
+		diff := 0.0
+		for i := range next {
+			next[i] /= norm
+			diff += math.Abs(next[i] - x[i])
+		}
+		x, next = next, x
+		if diff < spectralTolerance {
+			break
+		}
+	}
+
+	for i, node := range g.Nodes {
+		node.Eigenvector = x[i]
+	}
+}
@@ -1,6 +1,8 @@
 package graph
 
 import (
+	"cmp"
+	"encoding/binary"
 	"fmt"
 	"time"
 
@@ -16,24 +18,52 @@ const MapVendor = "IEDON.NET"
 // MapVersion is the current map binary format version.
 // Version 0: legacy (no version field, no AF on links)
 // Version 2: added address family (af) bitmask on links
-const MapVersion = 2
+// Version 3: added feeder peers, path attributes, link tiers and observation
+// counts, source status, directional, spectral, per AF and resilience
+// centrality, relationships, customer cones, communities, route validation
+// and withdrawn links. All fields are additive, version 2 readers ignore them.
+const MapVersion = 3
 
-// BuildGraph builds a Graph protobuf message from MRT processing results
-func BuildGraph(result *mrt.Result, asnDescriptions map[uint32]string) *pb.Graph {
+// AF bitmasks of the subgraphs with their own centrality
+const (
+	afSubgraphIPv4      = 1     // IPv4 unicast
+	afSubgraphIPv6      = 2     // IPv6 unicast
+	afSubgraphMulticast = 4 | 8 // IPv4 and IPv6 multicast
+)
+
+// BuildGraph builds a Graph protobuf message from MRT processing results,
+// ranking nodes by the dn42Index with the given weights. The centrality graph
+// of the combined address families is returned along with it.
+func BuildGraph(result *mrt.Result, asnDescriptions map[uint32]string, weights centrality.Weights) (*pb.Graph, *centrality.Graph) {
 	graph := &pb.Graph{
 		Metadata: buildMetadata(result),
 	}
 
 	nodeList, asnToIndex := collectSortedNodes(result)
-	centralityGraph := centrality.NewGraph()
+	centralityGraph := centrality.NewGraph(weights)
 
+	var peerToIndex map[*mrt.Peer]uint32
+	graph.Peers, peerToIndex = buildPeers(result)
 	graph.Nodes = buildNodes(nodeList, result, asnDescriptions, centralityGraph)
-	graph.Links = buildLinks(result, asnToIndex, centralityGraph)
+	graph.Links = buildLinks(result, asnToIndex, peerToIndex, centralityGraph)
+	graph.WithdrawnLinks = buildWithdrawnLinks(result, graph.Nodes, graph.Links)
+	applyRelationships(graph.Nodes, graph.Links, inferRelationships(result))
+	applyCustomerCones(graph.Nodes, graph.Links)
 
 	centralityGraph.CalculateCentrality()
-	applyCentrality(graph.Nodes, centralityGraph)
+	applyCentrality(graph.Nodes, centralityGraph, func(node *pb.Node, c *pb.Centrality) { node.Centrality = c })
+	applyResilience(graph.Nodes, graph.Links, centralityGraph)
+	applyCommunities(graph.Nodes, centralityGraph)
+
+	// Centrality of the address family subgraphs
+	applyCentrality(graph.Nodes, subgraphCentrality(graph.Nodes, graph.Links, afSubgraphIPv4, weights),
+		func(node *pb.Node, c *pb.Centrality) { node.CentralityIpv4 = c })
+	applyCentrality(graph.Nodes, subgraphCentrality(graph.Nodes, graph.Links, afSubgraphIPv6, weights),
+		func(node *pb.Node, c *pb.Centrality) { node.CentralityIpv6 = c })
+	applyCentrality(graph.Nodes, subgraphCentrality(graph.Nodes, graph.Links, afSubgraphMulticast, weights),
+		func(node *pb.Node, c *pb.Centrality) { node.CentralityMulticast = c })
 
-	return graph
+	return graph, centralityGraph
 }
 
 func buildMetadata(result *mrt.Result) *pb.Metadata {
@@ -72,21 +102,80 @@ func collectSortedNodes(result *mrt.Result) ([]uint32, map[uint32]uint32) {
 	return nodeList, asnToIndex
 }
 
+// buildPeers collects the unique feeder peers from all peer index tables and
+// returns them with a mapping from MRT peer entries to their Graph.peers index.
+func buildPeers(result *mrt.Result) ([]*pb.Peer, map[*mrt.Peer]uint32) {
+	type peerKey struct {
+		collector string
+		ip        string
+		asn       uint32
+	}
+	seen := make(map[peerKey]uint32)
+	peerToIndex := make(map[*mrt.Peer]uint32)
+	var pbPeers []*pb.Peer
+
+	for _, table := range result.PeerTables {
+		for _, peer := range table.Peers {
+			key := peerKey{collector: table.CollectorBGPID, ip: peer.IP, asn: peer.ASN}
+			idx, exists := seen[key]
+			if !exists {
+				idx = uint32(len(pbPeers))
+				seen[key] = idx
+				pbPeers = append(pbPeers, &pb.Peer{
+					Asn:            peer.ASN,
+					Ip:             peer.IP,
+					BgpId:          peer.BGPID,
+					CollectorBgpId: table.CollectorBGPID,
+					ViewName:       table.ViewName,
+				})
+			}
+			peerToIndex[peer] = idx
+		}
+	}
+
+	return pbPeers, peerToIndex
+}
+
 func buildNodes(nodeList []uint32, result *mrt.Result, descriptions map[uint32]string, cg *centrality.Graph) []*pb.Node {
 	nodes := make([]*pb.Node, 0, len(nodeList))
+	pathFlags := collectNodePathFlags(result)
+	originAttrs := collectOriginAttributes(result)
 	for _, asn := range nodeList {
 		node := &pb.Node{
 			Asn:             asn,
 			Desc:            descriptions[asn],
 			Routes:          convertRoutes(result.Advertises[asn]),
 			RoutesMulticast: convertRoutes(result.AdvertisesMulticast[asn]),
+			PathFlags:       pathFlags[asn],
 		}
+		originAttrs[asn].apply(node)
 		nodes = append(nodes, node)
 		cg.AddNode(asn)
 	}
 	return nodes
 }
 
+// collectNodePathFlags marks every ASN that appears inside an AS_SET or
+// confederation segment with the corresponding path flag.
+func collectNodePathFlags(result *mrt.Result) map[uint32]uint32 {
+	flags := make(map[uint32]uint32)
+	for _, asp := range result.ASPaths {
+		if asp.Flags == 0 {
+			continue
+		}
+		for _, seg := range asp.Segments {
+			flag := mrt.SegmentFlag(seg.Type)
+			if flag == 0 {
+				continue
+			}
+			for _, asn := range seg.ASNs {
+				flags[asn] |= flag
+			}
+		}
+	}
+	return flags
+}
+
 // convertRoutes converts MRT route entries into protobuf Route messages.
 func convertRoutes(routes []mrt.Route) []*pb.Route {
 	if len(routes) == 0 {
@@ -128,13 +217,28 @@ func convertRoute(route mrt.Route) *pb.Route {
 	return pbRoute
 }
 
-func buildLinks(result *mrt.Result, asnToIndex map[uint32]uint32, cg *centrality.Graph) []*pb.Link {
+func buildLinks(result *mrt.Result, asnToIndex map[uint32]uint32, peerToIndex map[*mrt.Peer]uint32, cg *centrality.Graph) []*pb.Link {
 	links := make(map[string]*pb.Link)
 	var pbLinks []*pb.Link
 
+	// Distinct AS paths and prefixes seen over each link. Paths are numbered
+	// once, so links only hold the IDs of the paths over them.
+	pathIDs := make(map[string]uint32)
+	linkPaths := make(map[*pb.Link]map[uint32]struct{})
+	linkPrefixes := make(map[*pb.Link]map[mrt.Route]struct{})
+
 	for _, asp := range result.ASPaths {
-		for i := range len(asp.Path) - 1 {
-			src, dst := asp.Path[i], asp.Path[i+1]
+		peerIdx, hasPeer := peerToIndex[asp.Peer]
+		tiers := dn42PathTiers(&asp)
+		path := pathKey(asp.Path)
+		pathID, ok := pathIDs[path]
+		if !ok {
+			pathID = uint32(len(pathIDs))
+			pathIDs[path] = pathID
+		}
+		// Only AS_SEQUENCE neighbours are adjacent, set members have no known order
+		for _, adj := range asp.Adjacencies() {
+			src, dst := adj[0], adj[1]
 
 			// Skip self-loops (AS prepending)
 			if src == dst {
@@ -148,37 +252,170 @@ func buildLinks(result *mrt.Result, asnToIndex map[uint32]uint32, cg *centrality
 			}
 
 			key := getLinkKey(srcIdx, dstIdx)
-			if existing, exists := links[key]; exists {
+			link, exists := links[key]
+			if exists {
 				// If the link already exists, combine AF bitmasks (e.g. if one path is IPv4 and another is IPv6, unicast and/or multicast)
-				existing.Af |= asp.AF
+				link.Af |= asp.AF
+				link.PathFlags |= asp.Flags
+				link.LastAnnounced = max(link.LastAnnounced, uint64(asp.Timestamp))
 			} else {
-				link := &pb.Link{
-					Source: srcIdx,
-					Target: dstIdx,
-					Af:     asp.AF,
+				link = &pb.Link{
+					Source:        srcIdx,
+					Target:        dstIdx,
+					Af:            asp.AF,
+					PathFlags:     asp.Flags,
+					LastAnnounced: uint64(asp.Timestamp),
+					LastWithdrawn: uint64(result.Withdrawals[adj]),
 				}
 				links[key] = link
 				pbLinks = append(pbLinks, link)
 				cg.AddLink(src, dst)
+				linkPaths[link] = make(map[uint32]struct{})
+				linkPrefixes[link] = make(map[mrt.Route]struct{})
+			}
+
+			linkPaths[link][pathID] = struct{}{}
+			linkPrefixes[link][asp.Prefix] = struct{}{}
+
+			applyLinkTiers(link, tiers)
+
+			// Record which feeder peers have seen this link
+			if hasPeer && !slices.Contains(link.Peers, peerIdx) {
+				link.Peers = append(link.Peers, peerIdx)
 			}
 		}
 	}
 
+	for _, link := range pbLinks {
+		link.PathCount = uint32(len(linkPaths[link]))
+		link.PrefixCount = uint32(len(linkPrefixes[link]))
+	}
+
 	return pbLinks
 }
 
-func applyCentrality(nodes []*pb.Node, cg *centrality.Graph) {
+// buildWithdrawnLinks lists the withdrawn adjacencies that are no longer
+// announced on any path, sorted by source and target ASN
+func buildWithdrawnLinks(result *mrt.Result, nodes []*pb.Node, links []*pb.Link) []*pb.WithdrawnLink {
+	announced := make(map[[2]uint32]struct{}, len(links))
+	for _, link := range links {
+		announced[[2]uint32{nodes[link.Source].Asn, nodes[link.Target].Asn}] = struct{}{}
+	}
+
+	var withdrawn []*pb.WithdrawnLink
+	for adj, timestamp := range result.Withdrawals {
+		if _, ok := announced[adj]; ok || adj[0] == adj[1] {
+			continue
+		}
+		withdrawn = append(withdrawn, &pb.WithdrawnLink{
+			Source:        adj[0],
+			Target:        adj[1],
+			LastWithdrawn: uint64(timestamp),
+		})
+	}
+	slices.SortFunc(withdrawn, func(a, b *pb.WithdrawnLink) int {
+		if c := cmp.Compare(a.Source, b.Source); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Target, b.Target)
+	})
+	return withdrawn
+}
+
+// pathKey returns a comparable key of an AS path
+func pathKey(path []uint32) string {
+	b := make([]byte, 4*len(path))
+	for i, asn := range path {
+		binary.BigEndian.PutUint32(b[4*i:], asn)
+	}
+	return string(b)
+}
+
+// subgraphCentrality calculates the centrality of the subgraph formed by the
+// links of the given AF bitmask and the nodes they connect
+func subgraphCentrality(nodes []*pb.Node, links []*pb.Link, afMask uint32, weights centrality.Weights) *centrality.Graph {
+	cg := centrality.NewGraph(weights)
+
+	inSubgraph := make([]bool, len(nodes))
+	for _, link := range links {
+		if link.Af&afMask != 0 {
+			inSubgraph[link.Source] = true
+			inSubgraph[link.Target] = true
+		}
+	}
+	for i, node := range nodes {
+		if inSubgraph[i] {
+			cg.AddNode(node.Asn)
+		}
+	}
+	for _, link := range links {
+		if link.Af&afMask != 0 {
+			cg.AddLink(nodes[link.Source].Asn, nodes[link.Target].Asn)
+		}
+	}
+
+	// The per AF centrality only feeds the rankings
+	cg.CalculateMetrics()
+	return cg
+}
+
+// applyCentrality stores the centrality of every node in cg with set
+func applyCentrality(nodes []*pb.Node, cg *centrality.Graph, set func(node *pb.Node, c *pb.Centrality)) {
 	for _, node := range nodes {
 		cn := cg.GetNode(node.Asn)
 		if cn == nil {
 			continue
 		}
-		node.Centrality = &pb.Centrality{
-			Degree:      cn.Degree,
-			Betweenness: cn.Betweenness,
-			Closeness:   cn.Closeness,
-			Index:       cn.Index,
-			Ranking:     cn.Ranking,
+		set(node, &pb.Centrality{
+			Degree:         cn.Degree,
+			Betweenness:    cn.Betweenness,
+			Closeness:      cn.Closeness,
+			Index:          cn.Index,
+			Ranking:        cn.Ranking,
+			Pagerank:       cn.PageRank,
+			Eigenvector:    cn.Eigenvector,
+			Harmonic:       cn.Harmonic,
+			InDegree:       cn.InDegree,
+			OutDegree:      cn.OutDegree,
+			InBetweenness:  cn.InBetweenness,
+			OutBetweenness: cn.OutBetweenness,
+		})
+	}
+}
+
+// applyResilience flags articulation points and bridges and stores the k-core
+// numbers calculated on the undirected graph
+func applyResilience(nodes []*pb.Node, links []*pb.Link, cg *centrality.Graph) {
+	for _, node := range nodes {
+		cn := cg.GetNode(node.Asn)
+		if cn == nil {
+			continue
+		}
+		node.ArticulationPoint = cn.Articulation
+		node.ArticulationCutOff = cn.ArticulationCutOff
+		node.CoreNumber = cn.CoreNumber
+	}
+
+	bridges := make(map[[2]uint32]uint32, len(cg.Bridges()))
+	for _, b := range cg.Bridges() {
+		bridges[[2]uint32{b.Source, b.Target}] = b.CutOff
+		bridges[[2]uint32{b.Target, b.Source}] = b.CutOff
+	}
+	for _, link := range links {
+		cutOff, ok := bridges[[2]uint32{nodes[link.Source].Asn, nodes[link.Target].Asn}]
+		if !ok {
+			continue
+		}
+		link.Bridge = true
+		link.BridgeCutOff = cutOff
+	}
+}
+
+// applyCommunities stores the community of every node
+func applyCommunities(nodes []*pb.Node, cg *centrality.Graph) {
+	for _, node := range nodes {
+		if cn := cg.GetNode(node.Asn); cn != nil {
+			node.Community = cn.Community
 		}
 	}
 }
@@ -0,0 +1,160 @@
+package graph
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/iedon/dn42_map_go/mrt"
+	pb "github.com/iedon/dn42_map_go/proto"
+)
+
+// DN42 community scheme, see https://dn42.dev/howto/BGP-communities
+const (
+	dn42CommunityASN = 64511
+
+	latencyMin   = 1 // 64511:1 - 64511:9, latency tiers
+	latencyMax   = 9
+	bandwidthMin = 21 // 64511:21 - 64511:29, bandwidth tiers
+	bandwidthMax = 29
+	cryptoMin    = 31 // 64511:31 - 64511:34, encryption level
+	cryptoMax    = 34
+	regionMin    = 41 // 64511:41 - 64511:70, region of origin
+	regionMax    = 70
+	countryMin   = 1000 // 64511:1000 - 64511:1999, country of origin (1000 + ISO 3166-1 numeric)
+	countryMax   = 1999
+)
+
+// pathTiers holds the DN42 link characteristics carried by a path. The values
+// are aggregated along the path: worst latency, lowest bandwidth and weakest
+// encryption of all links traversed. 0 means not tagged.
+type pathTiers struct {
+	latency   uint32 // 1-9
+	bandwidth uint32 // 1-9
+	crypto    uint32 // 1-4
+}
+
+// dn42PathTiers extracts the latency, bandwidth and crypto tiers of a path
+func dn42PathTiers(asp *mrt.ASPath) pathTiers {
+	var tiers pathTiers
+	for _, c := range asp.Communities {
+		if c>>16 != dn42CommunityASN {
+			continue
+		}
+		switch v := c & 0xFFFF; {
+		case v >= latencyMin && v <= latencyMax:
+			tiers.latency = max(tiers.latency, v)
+		case v >= bandwidthMin && v <= bandwidthMax:
+			tiers.bandwidth = minTier(tiers.bandwidth, v-bandwidthMin+1)
+		case v >= cryptoMin && v <= cryptoMax:
+			tiers.crypto = minTier(tiers.crypto, v-cryptoMin+1)
+		}
+	}
+	return tiers
+}
+
+// minTier returns the lower of two tiers, ignoring unset (0) values
+func minTier(a, b uint32) uint32 {
+	if a == 0 || b < a {
+		return b
+	}
+	return a
+}
+
+// applyLinkTiers narrows the known characteristics of a link with the tiers of
+// a path traversing it. Since path values are aggregated, a link is at least as
+// fast, wide and secure as the best path observed over it.
+func applyLinkTiers(link *pb.Link, tiers pathTiers) {
+	if tiers.latency != 0 {
+		link.Latency = minTier(link.Latency, tiers.latency)
+	}
+	link.Bandwidth = max(link.Bandwidth, tiers.bandwidth)
+	link.Crypto = max(link.Crypto, tiers.crypto)
+}
+
+// originAttributes collects the attributes an AS attaches to its own prefixes
+type originAttributes struct {
+	originTypes      uint32
+	regions          map[uint32]struct{}
+	countries        map[uint32]struct{}
+	communities      map[uint32]struct{}
+	largeCommunities map[mrt.LargeCommunity]struct{}
+}
+
+// collectOriginAttributes gathers ORIGIN and community attributes of all paths
+// by their origin AS
+func collectOriginAttributes(result *mrt.Result) map[uint32]*originAttributes {
+	attrs := make(map[uint32]*originAttributes)
+	for _, asp := range result.ASPaths {
+		origin := asp.Origin()
+		oa, ok := attrs[origin]
+		if !ok {
+			oa = &originAttributes{
+				regions:          make(map[uint32]struct{}),
+				countries:        make(map[uint32]struct{}),
+				communities:      make(map[uint32]struct{}),
+				largeCommunities: make(map[mrt.LargeCommunity]struct{}),
+			}
+			attrs[origin] = oa
+		}
+
+		oa.originTypes |= 1 << asp.OriginType
+		for _, c := range asp.Communities {
+			oa.communities[c] = struct{}{}
+			if c>>16 != dn42CommunityASN {
+				continue
+			}
+			switch v := c & 0xFFFF; {
+			case v >= regionMin && v <= regionMax:
+				oa.regions[v] = struct{}{}
+			case v >= countryMin && v <= countryMax:
+				oa.countries[v-countryMin] = struct{}{}
+			}
+		}
+		for _, lc := range asp.LargeCommunities {
+			oa.largeCommunities[lc] = struct{}{}
+		}
+	}
+	return attrs
+}
+
+// apply stores the collected origin attributes on a node
+func (oa *originAttributes) apply(node *pb.Node) {
+	if oa == nil {
+		return
+	}
+
+	node.OriginTypes = oa.originTypes
+	node.Regions = sortedKeys(oa.regions)
+	node.Countries = sortedKeys(oa.countries)
+	node.Communities = sortedKeys(oa.communities)
+
+	for lc := range oa.largeCommunities {
+		node.LargeCommunities = append(node.LargeCommunities, &pb.LargeCommunity{
+			GlobalAdmin: lc.GlobalAdmin,
+			LocalData1:  lc.LocalData1,
+			LocalData2:  lc.LocalData2,
+		})
+	}
+	slices.SortFunc(node.LargeCommunities, func(a, b *pb.LargeCommunity) int {
+		if c := cmp.Compare(a.GlobalAdmin, b.GlobalAdmin); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.LocalData1, b.LocalData1); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.LocalData2, b.LocalData2)
+	})
+}
+
+// sortedKeys returns the keys of a set in ascending order
+func sortedKeys(set map[uint32]struct{}) []uint32 {
+	if len(set) == 0 {
+		return nil
+	}
+	keys := make([]uint32, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,84 @@
+package graph
+
+import (
+	pb "github.com/iedon/dn42_map_go/proto"
+)
+
+// routeKey identifies a prefix independent of the Route message it came from
+type routeKey struct {
+	length uint32
+	isIPv6 bool
+	ipv4   uint32
+	ipv6   [4]uint32
+}
+
+func newRouteKey(route *pb.Route) routeKey {
+	key := routeKey{length: route.Length}
+	switch ip := route.Ip.(type) {
+	case *pb.Route_Ipv4:
+		key.ipv4 = ip.Ipv4
+	case *pb.Route_Ipv6:
+		key.isIPv6 = true
+		key.ipv6 = [4]uint32{ip.Ipv6.HighH32, ip.Ipv6.HighL32, ip.Ipv6.LowH32, ip.Ipv6.LowL32}
+	}
+	return key
+}
+
+// countPrefixes adds the distinct prefixes of routes to seen and returns how many were new
+func countPrefixes(routes []*pb.Route, seen map[routeKey]struct{}) uint32 {
+	var added uint32
+	for _, route := range routes {
+		key := newRouteKey(route)
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			added++
+		}
+	}
+	return added
+}
+
+// applyCustomerCones sets the customer cone of every node: the node itself
+// and all ASes reachable from it over inferred provider->customer links,
+// with the distinct unicast and multicast prefixes they originate
+func applyCustomerCones(nodes []*pb.Node, links []*pb.Link) {
+	customers := make([][]uint32, len(nodes))
+	for _, link := range links {
+		switch link.Relationship {
+		case pb.Relationship_RELATIONSHIP_PROVIDER_TO_CUSTOMER:
+			customers[link.Source] = append(customers[link.Source], link.Target)
+		case pb.Relationship_RELATIONSHIP_CUSTOMER_TO_PROVIDER:
+			customers[link.Target] = append(customers[link.Target], link.Source)
+		}
+	}
+
+	// Inferred relationships may contain cycles, so walk the cone of every
+	// node separately instead of summing the cones of its customers
+	visited := make([]int, len(nodes))
+	for i := range visited {
+		visited[i] = -1
+	}
+	var queue []uint32
+	for i, node := range nodes {
+		cone := &pb.CustomerCone{}
+		prefixes := make(map[routeKey]struct{})
+		prefixesMulticast := make(map[routeKey]struct{})
+
+		visited[i] = i
+		queue = append(queue[:0], uint32(i))
+		for head := 0; head < len(queue); head++ {
+			member := nodes[queue[head]]
+			cone.Asns++
+			cone.Prefixes += countPrefixes(member.Routes, prefixes)
+			cone.PrefixesMulticast += countPrefixes(member.RoutesMulticast, prefixesMulticast)
+
+			for _, customer := range customers[queue[head]] {
+				if visited[customer] != i {
+					visited[customer] = i
+					queue = append(queue, customer)
+				}
+			}
+		}
+
+		node.CustomerCone = cone
+	}
+}
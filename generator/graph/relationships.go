@@ -0,0 +1,181 @@
+package graph
+
+import (
+	"github.com/iedon/dn42_map_go/mrt"
+	pb "github.com/iedon/dn42_map_go/proto"
+)
+
+// Relationship inference parameters
+const (
+	peerDegreeRatio  = 60.0 // Maximum degree ratio of two ASes inferred as peers (Gao's R)
+	transitDominance = 0.2  // Minimum vote margin to infer provider->customer
+)
+
+// asPair is an unordered AS adjacency, lower ASN first
+type asPair [2]uint32
+
+func newASPair(a, b uint32) asPair {
+	if a > b {
+		return asPair{b, a}
+	}
+	return asPair{a, b}
+}
+
+// relationshipVotes collects the evidence for the relationship of an AS pair
+type relationshipVotes struct {
+	lowProvides   int  // Paths on which pair[0] provides transit to pair[1]
+	highProvides  int  // Paths on which pair[1] provides transit to pair[0]
+	peerCandidate bool // Seen between the top AS of a path and its higher degree neighbour
+	transit       bool // Seen away from the top of a path, so not a peering link
+}
+
+// relationship is the inferred relationship of an AS pair
+type relationship struct {
+	provider   uint32 // Provider AS for provider->customer, 0 otherwise
+	peer       bool
+	confidence float64
+}
+
+// forLink returns the relationship seen from the source of a link
+func (r relationship) forLink(source uint32) pb.Relationship {
+	switch {
+	case r.peer:
+		return pb.Relationship_RELATIONSHIP_PEER
+	case r.provider == 0:
+		return pb.Relationship_RELATIONSHIP_UNKNOWN
+	case r.provider == source:
+		return pb.Relationship_RELATIONSHIP_PROVIDER_TO_CUSTOMER
+	}
+	return pb.Relationship_RELATIONSHIP_CUSTOMER_TO_PROVIDER
+}
+
+// pathChains splits the AS_SEQUENCE adjacencies of a path into chains of
+// consecutive ASes without prepending
+func pathChains(asp *mrt.ASPath) [][]uint32 {
+	var chains [][]uint32
+	var chain []uint32
+	for _, adj := range asp.Adjacencies() {
+		if adj[0] == adj[1] {
+			continue
+		}
+		if len(chain) == 0 || chain[len(chain)-1] != adj[0] {
+			if len(chain) > 1 {
+				chains = append(chains, chain)
+			}
+			chain = []uint32{adj[0]}
+		}
+		chain = append(chain, adj[1])
+	}
+	if len(chain) > 1 {
+		chains = append(chains, chain)
+	}
+	return chains
+}
+
+// inferRelationships infers provider->customer and peering relationships
+// with a Gao-style valley-free heuristic. The AS with the highest degree on
+// a path is taken as its top provider. ASes before it received the route
+// from their provider, ASes after it announced it to their provider. A link
+// only ever seen between the top of paths and its higher degree neighbour,
+// with ASes of similar degree, is a peering link.
+func inferRelationships(result *mrt.Result) map[asPair]relationship {
+	var chains [][]uint32
+	for i := range result.ASPaths {
+		chains = append(chains, pathChains(&result.ASPaths[i])...)
+	}
+
+	// Degree on the undirected AS graph
+	adjacent := make(map[asPair]struct{})
+	degree := make(map[uint32]int)
+	for _, chain := range chains {
+		for i := 0; i+1 < len(chain); i++ {
+			pair := newASPair(chain[i], chain[i+1])
+			if _, ok := adjacent[pair]; !ok {
+				adjacent[pair] = struct{}{}
+				degree[pair[0]]++
+				degree[pair[1]]++
+			}
+		}
+	}
+
+	// Collect transit votes, the path is ordered from the collector to the origin
+	votes := make(map[asPair]*relationshipVotes, len(adjacent))
+	for _, chain := range chains {
+		top := 0
+		for i, asn := range chain {
+			if degree[asn] > degree[chain[top]] {
+				top = i
+			}
+		}
+
+		// Only the link from the top to its higher degree neighbour may be a
+		// peering link, the other side of the top is provider->customer
+		candidate := top
+		if top == len(chain)-1 || (top > 0 && degree[chain[top-1]] > degree[chain[top+1]]) {
+			candidate = top - 1
+		}
+
+		for i := 0; i+1 < len(chain); i++ {
+			pair := newASPair(chain[i], chain[i+1])
+			v := votes[pair]
+			if v == nil {
+				v = &relationshipVotes{}
+				votes[pair] = v
+			}
+
+			provider := chain[i]
+			if i < top {
+				provider = chain[i+1]
+			}
+			if provider == pair[0] {
+				v.lowProvides++
+			} else {
+				v.highProvides++
+			}
+
+			if i == candidate {
+				v.peerCandidate = true
+			} else {
+				v.transit = true
+			}
+		}
+	}
+
+	relationships := make(map[asPair]relationship, len(votes))
+	for pair, v := range votes {
+		lowDeg, highDeg := float64(degree[pair[0]]), float64(degree[pair[1]])
+		ratio := max(lowDeg, highDeg) / min(lowDeg, highDeg)
+
+		if v.peerCandidate && !v.transit && ratio <= peerDegreeRatio {
+			relationships[pair] = relationship{peer: true, confidence: 1 / ratio}
+			continue
+		}
+
+		total := float64(v.lowProvides + v.highProvides)
+		margin := float64(v.lowProvides-v.highProvides) / total
+		switch {
+		case margin >= transitDominance:
+			relationships[pair] = relationship{provider: pair[0], confidence: margin * total / (total + 1)}
+		case margin <= -transitDominance:
+			relationships[pair] = relationship{provider: pair[1], confidence: -margin * total / (total + 1)}
+		default:
+			// Conflicting evidence, e.g. siblings or a partial transit
+			relationships[pair] = relationship{}
+		}
+	}
+
+	return relationships
+}
+
+// applyRelationships labels every link with the inferred relationship of its ASes
+func applyRelationships(nodes []*pb.Node, links []*pb.Link, relationships map[asPair]relationship) {
+	for _, link := range links {
+		source, target := nodes[link.Source].Asn, nodes[link.Target].Asn
+		r, ok := relationships[newASPair(source, target)]
+		if !ok {
+			continue
+		}
+		link.Relationship = r.forLink(source)
+		link.RelationshipConfidence = r.confidence
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"slices"
+
+	pb "github.com/iedon/dn42_map_go/proto"
+)
+
+// graphIndex is a read-only lookup view of a graph snapshot. It is built
+// once when a snapshot is swapped in and replaced together with it.
+type graphIndex struct {
+	nodes           map[uint32]*pb.Node
+	neighbors       map[uint32][]uint32 // Sorted, in either link direction
+	upstreams       map[uint32][]uint32 // Sorted inferred providers
+	downstreams     map[uint32][]uint32 // Sorted inferred customers
+	peerings        map[uint32][]uint32 // Sorted inferred peers
+	routes          *prefixTrie
+	routesMulticast *prefixTrie
+}
+
+// newGraphIndex builds the lookup view of a graph
+func newGraphIndex(graph *pb.Graph) *graphIndex {
+	idx := &graphIndex{
+		nodes:           make(map[uint32]*pb.Node, len(graph.Nodes)),
+		neighbors:       make(map[uint32][]uint32, len(graph.Nodes)),
+		upstreams:       make(map[uint32][]uint32),
+		downstreams:     make(map[uint32][]uint32),
+		peerings:        make(map[uint32][]uint32),
+		routes:          &prefixTrie{},
+		routesMulticast: &prefixTrie{},
+	}
+
+	for _, node := range graph.Nodes {
+		idx.nodes[node.Asn] = node
+		for _, route := range node.Routes {
+			if prefix, ok := routePrefix(route); ok {
+				idx.routes.insert(prefix, node.Asn)
+			}
+		}
+		for _, route := range node.RoutesMulticast {
+			if prefix, ok := routePrefix(route); ok {
+				idx.routesMulticast.insert(prefix, node.Asn)
+			}
+		}
+	}
+
+	// Links reference nodes by their index in graph.Nodes
+	for _, link := range graph.Links {
+		if int(link.Source) >= len(graph.Nodes) || int(link.Target) >= len(graph.Nodes) {
+			continue
+		}
+		src := graph.Nodes[link.Source].Asn
+		dst := graph.Nodes[link.Target].Asn
+		idx.neighbors[src] = append(idx.neighbors[src], dst)
+		idx.neighbors[dst] = append(idx.neighbors[dst], src)
+
+		switch link.Relationship {
+		case pb.Relationship_RELATIONSHIP_PROVIDER_TO_CUSTOMER:
+			idx.downstreams[src] = append(idx.downstreams[src], dst)
+			idx.upstreams[dst] = append(idx.upstreams[dst], src)
+		case pb.Relationship_RELATIONSHIP_CUSTOMER_TO_PROVIDER:
+			idx.upstreams[src] = append(idx.upstreams[src], dst)
+			idx.downstreams[dst] = append(idx.downstreams[dst], src)
+		case pb.Relationship_RELATIONSHIP_PEER:
+			idx.peerings[src] = append(idx.peerings[src], dst)
+			idx.peerings[dst] = append(idx.peerings[dst], src)
+		}
+	}
+	for _, m := range []map[uint32][]uint32{idx.neighbors, idx.upstreams, idx.downstreams, idx.peerings} {
+		for asn, list := range m {
+			slices.Sort(list)
+			m[asn] = slices.Compact(list)
+		}
+	}
+
+	return idx
+}
+
+// Node returns the node of an ASN, or nil if it is not in the graph
+func (idx *graphIndex) Node(asn uint32) *pb.Node {
+	return idx.nodes[asn]
+}
+
+// Neighbors returns the sorted ASNs adjacent to an ASN
+func (idx *graphIndex) Neighbors(asn uint32) []uint32 {
+	return idx.neighbors[asn]
+}
+
+// Upstreams returns the sorted inferred providers of an ASN
+func (idx *graphIndex) Upstreams(asn uint32) []uint32 {
+	return idx.upstreams[asn]
+}
+
+// Downstreams returns the sorted inferred customers of an ASN
+func (idx *graphIndex) Downstreams(asn uint32) []uint32 {
+	return idx.downstreams[asn]
+}
+
+// Peerings returns the sorted inferred peers of an ASN
+func (idx *graphIndex) Peerings(asn uint32) []uint32 {
+	return idx.peerings[asn]
+}
+
+// LookupRoute returns the most specific unicast or multicast route
+// covering addr and the ASNs originating it
+func (idx *graphIndex) LookupRoute(addr netip.Addr, multicast bool) (netip.Prefix, []uint32, bool) {
+	if multicast {
+		return idx.routesMulticast.lookup(addr)
+	}
+	return idx.routes.lookup(addr)
+}
+
+// routePrefix converts a protobuf Route to a netip.Prefix
+func routePrefix(route *pb.Route) (netip.Prefix, bool) {
+	var addr netip.Addr
+	switch ip := route.Ip.(type) {
+	case *pb.Route_Ipv4:
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], ip.Ipv4)
+		addr = netip.AddrFrom4(b)
+	case *pb.Route_Ipv6:
+		var b [16]byte
+		binary.BigEndian.PutUint32(b[0:4], ip.Ipv6.HighH32)
+		binary.BigEndian.PutUint32(b[4:8], ip.Ipv6.HighL32)
+		binary.BigEndian.PutUint32(b[8:12], ip.Ipv6.LowH32)
+		binary.BigEndian.PutUint32(b[12:16], ip.Ipv6.LowL32)
+		addr = netip.AddrFrom16(b)
+	default:
+		return netip.Prefix{}, false
+	}
+
+	prefix, err := addr.Prefix(int(route.Length))
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	return prefix, true
+}
+
+// prefixTrie is a binary trie of IPv4 and IPv6 prefixes for longest match lookups
+type prefixTrie struct {
+	v4, v6 trieNode
+}
+
+type trieNode struct {
+	children [2]*trieNode
+	prefix   netip.Prefix
+	origins  []uint32 // Sorted, non-empty if a prefix ends at this node
+}
+
+// root returns the trie root for the address family of addr
+func (t *prefixTrie) root(addr netip.Addr) *trieNode {
+	if addr.Is4() {
+		return &t.v4
+	}
+	return &t.v6
+}
+
+// addrBit returns bit i of addr, counted from the most significant bit
+func addrBit(b []byte, i int) int {
+	return int(b[i/8]>>(7-i%8)) & 1
+}
+
+// insert adds an origin ASN of a prefix
+func (t *prefixTrie) insert(prefix netip.Prefix, asn uint32) {
+	addr := prefix.Addr()
+	b := addr.AsSlice()
+	node := t.root(addr)
+	for i := 0; i < prefix.Bits(); i++ {
+		bit := addrBit(b, i)
+		if node.children[bit] == nil {
+			node.children[bit] = &trieNode{}
+		}
+		node = node.children[bit]
+	}
+
+	node.prefix = prefix
+	if i, found := slices.BinarySearch(node.origins, asn); !found {
+		node.origins = slices.Insert(node.origins, i, asn)
+	}
+}
+
+// lookup returns the most specific prefix covering addr and its origins
+func (t *prefixTrie) lookup(addr netip.Addr) (netip.Prefix, []uint32, bool) {
+	addr = addr.Unmap()
+	b := addr.AsSlice()
+	node := t.root(addr)
+
+	var best *trieNode
+	for i := 0; node != nil; i++ {
+		if len(node.origins) > 0 {
+			best = node
+		}
+		if i == len(b)*8 {
+			break
+		}
+		node = node.children[addrBit(b, i)]
+	}
+
+	if best == nil {
+		return netip.Prefix{}, nil, false
+	}
+	return best.prefix, best.origins, true
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Generation job phases
+const (
+	phaseIdle        = "idle"
+	phaseDownloading = "downloading"  // Downloading and decoding MRT files
+	phaseParsing     = "parsing"      // Merging decoded MRT data, applying updates
+	phaseRegistry    = "registry"     // Reading ASN descriptions from the registry
+	phaseCentrality  = "centrality"   // Building the graph and its centrality metrics
+	phaseWriting     = "writing"      // Serializing and writing the output file
+	phasePostCommand = "post-command" // Running the post-generation command
+)
+
+// jobKind is the kind of a generation job. Higher kinds supersede lower ones
+// when requests are coalesced.
+type jobKind int
+
+const (
+	jobNone     jobKind = iota
+	jobUpdate           // Apply BGP4MP updates to the base RIB
+	jobGenerate         // Full generation from MRT dumps
+)
+
+func (k jobKind) String() string {
+	switch k {
+	case jobUpdate:
+		return "update"
+	case jobGenerate:
+		return "generate"
+	}
+	return ""
+}
+
+// PhaseTiming records when a job phase started and how long it took
+type PhaseTiming struct {
+	Phase    string    `json:"phase"`
+	Started  time.Time `json:"started"`
+	Duration float64   `json:"duration_seconds"`
+}
+
+// JobStatus reports the state of the generation job runner
+type JobStatus struct {
+	Running          bool          `json:"running"`
+	Kind             string        `json:"kind,omitempty"`
+	Phase            string        `json:"phase"`
+	Queued           string        `json:"queued,omitempty"`
+	Started          *time.Time    `json:"started,omitempty"`
+	Phases           []PhaseTiming `json:"phases"`
+	LastSuccess      *time.Time    `json:"last_success,omitempty"`
+	LastError        string        `json:"last_error,omitempty"`
+	LastErrorTime    *time.Time    `json:"last_error_time,omitempty"`
+	PostCommandError string        `json:"post_command_error,omitempty"`
+}
+
+// jobRunner runs generation jobs one at a time. Requests that arrive while
+// a job is running are coalesced into a single queued job.
+type jobRunner struct {
+	mu     sync.Mutex
+	queued jobKind
+	status JobStatus
+	run    func(job *job) error
+
+	// finished is called with the phase timings and outcome of every job
+	finished func(kind jobKind, phases []PhaseTiming, err error)
+}
+
+// job tracks the phases of a single generation run
+type job struct {
+	runner *jobRunner
+	kind   jobKind
+}
+
+// newJobRunner creates a job runner executing run for every job
+func newJobRunner(run func(job *job) error) *jobRunner {
+	return &jobRunner{
+		status: JobStatus{Phase: phaseIdle, Phases: []PhaseTiming{}},
+		run:    run,
+	}
+}
+
+// Request starts a job of the given kind, or queues it if one is running.
+// It reports whether the request started or queued a new job, false means
+// it was merged into an already queued one.
+func (r *jobRunner) Request(kind jobKind) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.status.Running {
+		merged := r.queued != jobNone
+		r.queued = max(r.queued, kind)
+		r.status.Queued = r.queued.String()
+		return !merged
+	}
+
+	r.status.Running = true
+	go r.loop(kind)
+	return true
+}
+
+// Run runs a job of the given kind synchronously
+func (r *jobRunner) Run(kind jobKind) error {
+	r.mu.Lock()
+	r.status.Running = true
+	r.mu.Unlock()
+
+	err := r.execute(kind)
+
+	r.mu.Lock()
+	r.status.Running = false
+	r.mu.Unlock()
+	return err
+}
+
+// Status returns a snapshot of the runner status
+func (r *jobRunner) Status() JobStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := r.status
+	status.Phases = append([]PhaseTiming{}, r.status.Phases...)
+	return status
+}
+
+// loop runs jobs until no more are queued
+func (r *jobRunner) loop(kind jobKind) {
+	for kind != jobNone {
+		r.execute(kind)
+
+		r.mu.Lock()
+		kind = r.queued
+		r.queued = jobNone
+		r.status.Queued = ""
+		if kind == jobNone {
+			r.status.Running = false
+		}
+		r.mu.Unlock()
+	}
+}
+
+// execute runs a single job and records its outcome
+func (r *jobRunner) execute(kind jobKind) error {
+	started := time.Now()
+
+	r.mu.Lock()
+	r.status.Kind = kind.String()
+	r.status.Started = &started
+	r.status.Phases = []PhaseTiming{}
+	r.status.PostCommandError = ""
+	r.mu.Unlock()
+
+	j := &job{runner: r, kind: kind}
+	err := r.run(j)
+	j.phase(phaseIdle)
+
+	finished := time.Now()
+	r.mu.Lock()
+	if err != nil {
+		r.status.LastError = err.Error()
+		r.status.LastErrorTime = &finished
+	} else {
+		r.status.LastSuccess = &finished
+	}
+	phases := append([]PhaseTiming{}, r.status.Phases...)
+	r.mu.Unlock()
+
+	if r.finished != nil {
+		r.finished(kind, phases, err)
+	}
+
+	if err != nil {
+		log.Printf("Map %s job failed: %v\n", kind, err)
+	}
+	return err
+}
+
+// phase ends the current phase of the job and starts the next one
+func (j *job) phase(phase string) {
+	r := j.runner
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if n := len(r.status.Phases); n > 0 {
+		last := &r.status.Phases[n-1]
+		last.Duration = now.Sub(last.Started).Seconds()
+	}
+
+	r.status.Phase = phase
+	if phase != phaseIdle {
+		r.status.Phases = append(r.status.Phases, PhaseTiming{Phase: phase, Started: now})
+	}
+}
+
+// postCommandFailed records an error of the post-generation command
+func (j *job) postCommandFailed(err error) {
+	j.runner.mu.Lock()
+	j.runner.status.PostCommandError = err.Error()
+	j.runner.mu.Unlock()
+}
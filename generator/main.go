@@ -7,28 +7,67 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
+
+	"github.com/iedon/dn42_map_go/centrality"
 )
 
 // Config structure
 type Config struct {
 	RegistryPath           string    `json:"registry_path"`
 	OutputFile             string    `json:"output_file"`
+	OutputRetention        int       `json:"output_retention"` // Number of previous maps kept as <output_file>.1 to .N
+	MinNodeRatio           float64   `json:"min_node_ratio"`   // Minimum node count relative to the previous map, 0 disables the check
+	MinLinkRatio           float64   `json:"min_link_ratio"`   // Minimum link count relative to the previous map, 0 disables the check
 	PostGenerationCommand  string    `json:"post_generation_command"`
+	PostGenerationTimeout  int       `json:"post_generation_timeout"` // Seconds before the post-generation command is killed, defaults to 300
 	DoNotGenerateOnEmpty   bool      `json:"do_not_generate_on_empty"`
 	MRTCollector           Collector `json:"mrt_collector"`
+	Ranking                Ranking   `json:"ranking"`
+	ROA                    ROAExport `json:"roa"`
+	RTR                    RTR       `json:"rtr"`
 	API                    API       `json:"api"`
 }
 
 // Collector configuration for MRT
 type Collector struct {
-	IPv4MRTDumpURL          string `json:"ipv4_mrt_dump_url"`
-	IPv6MRTDumpURL          string `json:"ipv6_mrt_dump_url"`
-	IPv4MulticastMRTDumpURL string `json:"ipv4_multicast_mrt_dump_url"`
-	IPv6MulticastMRTDumpURL string `json:"ipv6_multicast_mrt_dump_url"`
-	Username                string `json:"username"`
-	Password                string `json:"password"`
-	InsecureSkipVerify      bool   `json:"insecure_skip_verify"`
-	CustomDNSServer         string `json:"custom_dns_server"`
+	IPv4MRTDumpURL            string   `json:"ipv4_mrt_dump_url"`
+	IPv6MRTDumpURL            string   `json:"ipv6_mrt_dump_url"`
+	IPv4MulticastMRTDumpURL   string   `json:"ipv4_multicast_mrt_dump_url"`
+	IPv6MulticastMRTDumpURL   string   `json:"ipv6_multicast_mrt_dump_url"`
+	IPv4MRTUpdateURL          string   `json:"ipv4_mrt_update_url"`
+	IPv6MRTUpdateURL          string   `json:"ipv6_mrt_update_url"`
+	IPv4MulticastMRTUpdateURL string   `json:"ipv4_multicast_mrt_update_url"`
+	IPv6MulticastMRTUpdateURL string   `json:"ipv6_multicast_mrt_update_url"`
+	OptionalSources           []string `json:"optional_sources"`
+	Username                  string   `json:"username"`
+	Password                  string   `json:"password"`
+	InsecureSkipVerify        bool     `json:"insecure_skip_verify"`
+	CustomDNSServer           string   `json:"custom_dns_server"`
+}
+
+// Ranking configuration of the dn42Index
+type Ranking struct {
+	Weights  centrality.Weights            `json:"weights"`  // Weights of the default ranking, unset uses the built-in weights
+	Profiles map[string]centrality.Weights `json:"profiles"` // Additional named rankings for /ranking?profile=
+}
+
+// ROAExport configures the ROA tables written after each generation, empty paths are skipped
+type ROAExport struct {
+	BirdROA4File string `json:"bird_roa4_file"` // bird2 roa4 table
+	BirdROA6File string `json:"bird_roa6_file"` // bird2 roa6 table
+	JSONFile     string `json:"json_file"`      // rpki-client JSON
+}
+
+// RTR server configuration, intervals in seconds are sent to routers and default to 3600, 600 and 7200
+type RTR struct {
+	Enabled                bool   `json:"enabled"`
+	ListenAddr             string `json:"listen_addr"`
+	RefreshInterval        uint32 `json:"refresh_interval"`
+	RetryInterval          uint32 `json:"retry_interval"`
+	ExpireInterval         uint32 `json:"expire_interval"`
+	RegistryReloadInterval uint32 `json:"registry_reload_interval"` // Seconds between registry reloads outside of generations, defaults to 600
+	Standalone             bool   `json:"standalone"`               // Keep serving RTR after generating the map when the API is disabled
 }
 
 // API service configuration
@@ -44,6 +83,7 @@ var (
 	ipv4MRTDumpURL = flag.String("ipv4_mrt_dump_url", "", "Force MRT Dump IPv4 URL")
 	ipv6MRTDumpURL = flag.String("ipv6_mrt_dump_url", "", "Force MRT Dump IPv6 URL")
 	disableAPI     = flag.Bool("disable_api", false, "Disable API server mode (only generate map without serving API)")
+	rtrCheck       = flag.String("rtr_check", "", "Check the RTR server at this address with a test client and exit")
 )
 
 func loadConfig(path string) (*Config, error) {
@@ -63,6 +103,13 @@ func loadConfig(path string) (*Config, error) {
 func main() {
 	flag.Parse()
 
+	if *rtrCheck != "" {
+		if err := checkRTR(*rtrCheck); err != nil {
+			log.Fatalf("RTR check failed: %v\n", err)
+		}
+		return
+	}
+
 	// Load config file
 	config, err := loadConfig(*configFile)
 	if err != nil {
@@ -97,14 +144,38 @@ func main() {
 	server := NewServer(config)
 
 	// Register routes
-	http.HandleFunc("/asn/", server.handleASN)
-	http.HandleFunc("/generate", server.handleGenerate)
-	http.HandleFunc("/map", server.handleMap)
-	http.HandleFunc("/ranking", server.handleRanking)
+	http.HandleFunc("/asn/", server.instrument("/asn/", server.handleASN))
+	http.HandleFunc("/route/", server.instrument("/route/", server.handleRoute))
+	http.HandleFunc("/generate", server.instrument("/generate", server.handleGenerate))
+	http.HandleFunc("/generate/status", server.instrument("/generate/status", server.handleGenerateStatus))
+	http.HandleFunc("/update", server.instrument("/update", server.handleUpdate))
+	http.HandleFunc("/map", server.instrument("/map", server.handleMap))
+	http.HandleFunc("/ranking", server.instrument("/ranking", server.handleRanking))
+	http.HandleFunc("/resilience", server.instrument("/resilience", server.handleResilience))
+	http.HandleFunc("/simulate", server.instrument("/simulate", server.handleSimulate))
+	http.HandleFunc("/communities", server.instrument("/communities", server.handleCommunities))
+	http.HandleFunc("/validation", server.instrument("/validation", server.handleValidation))
+	http.HandleFunc("/roa", server.instrument("/roa", server.handleROA))
+	http.HandleFunc("/metrics", server.handleMetrics)
+
+	// The ROAs of the RTR server follow the registry independently of map
+	// generations
+	if server.rtr != nil {
+		interval := time.Duration(config.RTR.RegistryReloadInterval) * time.Second
+		if interval == 0 {
+			interval = defaultRegistryReloadInterval
+		}
+		go server.reloadRegistry(interval)
+		go func() {
+			if err := server.rtr.ListenAndServe(config.RTR.ListenAddr); err != nil {
+				log.Fatalf("Failed to start RTR server: %v\n", err)
+			}
+		}()
+	}
 
 	if config.API.Enabled {
 		// Generate map on startup
-		go server.generateMap()
+		server.jobs.Request(jobGenerate)
 
 		// Start the HTTP server
 		log.Printf("Starting HTTP server on %s\n", config.API.ListenAddr)
@@ -113,6 +184,10 @@ func main() {
 		}
 	} else {
 		log.Println("API server mode is disabled. Generating map...")
-		server.generateMap()
+		server.jobs.Run(jobGenerate)
+		if server.rtr != nil {
+			log.Println("Map generated, serving RTR standalone until stopped")
+			select {}
+		}
 	}
 }
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/iedon/dn42_map_go/proto"
+)
+
+// Metric names exposed on /metrics
+const (
+	metricGenerationRuns      = "dn42map_generation_runs_total"
+	metricPhaseDuration       = "dn42map_generation_phase_duration_seconds"
+	metricMRTBytes            = "dn42map_mrt_downloaded_bytes_total"
+	metricMRTEntries          = "dn42map_mrt_entries_parsed_total"
+	metricGraphNodes          = "dn42map_graph_nodes"
+	metricGraphLinks          = "dn42map_graph_links"
+	metricGraphPrefixes       = "dn42map_graph_prefixes"
+	metricGraphLastModified   = "dn42map_graph_last_modified_timestamp_seconds"
+	metricGraphAge            = "dn42map_graph_age_seconds"
+	metricHTTPRequests        = "dn42map_http_requests_total"
+	metricPostCommandRuns     = "dn42map_post_command_runs_total"
+	metricPostCommandExitCode = "dn42map_post_command_last_exit_code"
+)
+
+// metricFamily holds all label combinations of a single metric
+type metricFamily struct {
+	help   string
+	typ    string // "counter" or "gauge"
+	values map[string]float64
+}
+
+// Metrics is a minimal registry rendering the Prometheus text exposition format
+type Metrics struct {
+	mu       sync.Mutex
+	families map[string]*metricFamily
+}
+
+// NewMetrics creates the registry with all generator metrics
+func NewMetrics() *Metrics {
+	m := &Metrics{families: make(map[string]*metricFamily)}
+	m.register(metricGenerationRuns, "counter", "Map generation jobs by kind and result.")
+	m.register(metricPhaseDuration, "gauge", "Duration of each phase of the last generation job.")
+	m.register(metricMRTBytes, "counter", "Compressed MRT bytes downloaded per source.")
+	m.register(metricMRTEntries, "counter", "RIB entries and updates parsed per source.")
+	m.register(metricGraphNodes, "gauge", "Number of nodes in the current map.")
+	m.register(metricGraphLinks, "gauge", "Number of links in the current map.")
+	m.register(metricGraphPrefixes, "gauge", "Number of prefixes in the current map by type.")
+	m.register(metricGraphLastModified, "gauge", "Unix time the current map was generated.")
+	m.register(metricGraphAge, "gauge", "Seconds since the current map was generated.")
+	m.register(metricHTTPRequests, "counter", "HTTP requests by endpoint and status code.")
+	m.register(metricPostCommandRuns, "counter", "Post-generation command runs by exit code.")
+	m.register(metricPostCommandExitCode, "gauge", "Exit code of the last post-generation command run.")
+	return m
+}
+
+func (m *Metrics) register(name, typ, help string) {
+	m.families[name] = &metricFamily{help: help, typ: typ, values: make(map[string]float64)}
+}
+
+// formatLabels renders label name/value pairs as {name="value",...}
+func formatLabels(pairs []string) string {
+	if len(pairs) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(pairs[i])
+		sb.WriteString(`="`)
+		sb.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(pairs[i+1]))
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+// Add increments a metric by v, labels are given as name/value pairs
+func (m *Metrics) Add(name string, v float64, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if f, ok := m.families[name]; ok {
+		f.values[formatLabels(labels)] += v
+	}
+}
+
+// Set sets a metric to v, labels are given as name/value pairs
+func (m *Metrics) Set(name string, v float64, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if f, ok := m.families[name]; ok {
+		f.values[formatLabels(labels)] = v
+	}
+}
+
+// Reset removes all label combinations of a metric
+func (m *Metrics) Reset(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if f, ok := m.families[name]; ok {
+		clear(f.values)
+	}
+}
+
+// WriteTo renders all metrics in the Prometheus text exposition format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.families))
+	for name := range m.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		f := m.families[name]
+		if len(f.values) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "# HELP %s %s\n# TYPE %s %s\n", name, f.help, name, f.typ)
+
+		labels := make([]string, 0, len(f.values))
+		for l := range f.values {
+			labels = append(labels, l)
+		}
+		sort.Strings(labels)
+		for _, l := range labels {
+			fmt.Fprintf(&sb, "%s%s %s\n", name, l, formatValue(f.values[l]))
+		}
+	}
+
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+// formatValue formats a sample value as Prometheus expects it
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// instrument counts the requests handled by h under the given endpoint name
+func (s *Server) instrument(endpoint string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h(rec, r)
+		s.metrics.Add(metricHTTPRequests, 1, "endpoint", endpoint, "status", strconv.Itoa(rec.status))
+	}
+}
+
+// handleMetrics handles /metrics requests
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	s.graphMutex.RLock()
+	if s.graph != nil {
+		s.metrics.Set(metricGraphAge, time.Since(s.lastModified).Seconds())
+	}
+	s.graphMutex.RUnlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	s.metrics.WriteTo(w)
+}
+
+// recordJobMetrics records the outcome and phase durations of a finished job
+func (s *Server) recordJobMetrics(kind jobKind, phases []PhaseTiming, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	s.metrics.Add(metricGenerationRuns, 1, "kind", kind.String(), "result", result)
+
+	s.metrics.Reset(metricPhaseDuration)
+	for _, p := range phases {
+		s.metrics.Set(metricPhaseDuration, p.Duration, "phase", p.Phase)
+	}
+}
+
+// recordGraphMetrics records the size of a newly published map
+func (s *Server) recordGraphMetrics(graph *pb.Graph) {
+	var routes, routesMulticast int
+	for _, node := range graph.Nodes {
+		routes += len(node.Routes)
+		routesMulticast += len(node.RoutesMulticast)
+	}
+
+	s.metrics.Set(metricGraphNodes, float64(len(graph.Nodes)))
+	s.metrics.Set(metricGraphLinks, float64(len(graph.Links)))
+	s.metrics.Set(metricGraphPrefixes, float64(routes), "type", "unicast")
+	s.metrics.Set(metricGraphPrefixes, float64(routesMulticast), "type", "multicast")
+	s.metrics.Set(metricGraphLastModified, float64(s.lastModified.Unix()))
+}
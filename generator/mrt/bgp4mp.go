@@ -0,0 +1,292 @@
+package mrt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+)
+
+// Update represents a single route announcement or withdrawal from a
+// BGP4MP update stream
+type Update struct {
+	Withdraw bool
+	// Path of the announcement. For withdrawals only Prefix, PathID, AF,
+	// Peer and Timestamp are set.
+	Path ASPath
+}
+
+// nlri represents a prefix from a BGP UPDATE NLRI field
+type nlri struct {
+	route  Route
+	pathID uint32
+}
+
+// processBGP4MP processes BGP4MP and BGP4MP_ET type messages. Only UPDATE
+// messages received from peers are used, state changes and messages sent
+// by the collector itself are skipped.
+func (p *Processor) processBGP4MP(subType uint16, data []byte, timestamp uint32, result *Result, isMulticast bool) error {
+	var asn4, addPath bool
+	switch subType {
+	case 1: // BGP4MP_MESSAGE
+	case 4: // BGP4MP_MESSAGE_AS4
+		asn4 = true
+	case 8: // BGP4MP_MESSAGE_ADDPATH
+		addPath = true
+	case 9: // BGP4MP_MESSAGE_AS4_ADDPATH
+		asn4, addPath = true, true
+	default:
+		return nil
+	}
+
+	reader := bytes.NewReader(data)
+
+	// Read peer AS and local AS
+	var peerASN uint32
+	if asn4 {
+		var localASN uint32
+		if err := binary.Read(reader, binary.BigEndian, &peerASN); err != nil {
+			return err
+		}
+		if err := binary.Read(reader, binary.BigEndian, &localASN); err != nil {
+			return err
+		}
+	} else {
+		var peerASN16, localASN16 uint16
+		if err := binary.Read(reader, binary.BigEndian, &peerASN16); err != nil {
+			return err
+		}
+		if err := binary.Read(reader, binary.BigEndian, &localASN16); err != nil {
+			return err
+		}
+		peerASN = uint32(peerASN16)
+	}
+
+	// Read interface index and address family of the session
+	var ifIndex, afi uint16
+	if err := binary.Read(reader, binary.BigEndian, &ifIndex); err != nil {
+		return err
+	}
+	if err := binary.Read(reader, binary.BigEndian, &afi); err != nil {
+		return err
+	}
+
+	ipLen := 4
+	if afi == 2 {
+		ipLen = 16
+	}
+	peerIP := make([]byte, ipLen)
+	if _, err := io.ReadFull(reader, peerIP); err != nil {
+		return err
+	}
+	if _, err := reader.Seek(int64(ipLen), io.SeekCurrent); err != nil { // Local IP
+		return err
+	}
+
+	// Read BGP message header: marker, length, type
+	header := make([]byte, 19)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return err
+	}
+	if header[18] != 2 { // UPDATE
+		return nil
+	}
+
+	peer := p.updatePeer(result, net.IP(peerIP).String(), peerASN)
+	return p.processBGPUpdate(reader, result, peer, timestamp, asn4, addPath, isMulticast)
+}
+
+// updatePeer returns the shared peer entry for a BGP4MP session
+func (p *Processor) updatePeer(result *Result, ip string, asn uint32) *Peer {
+	p.Lock()
+	defer p.Unlock()
+
+	if result.updatePeers == nil {
+		result.updatePeers = &PeerIndexTable{}
+		result.PeerTables = append(result.PeerTables, result.updatePeers)
+	}
+	for _, peer := range result.updatePeers.Peers {
+		if peer.IP == ip && peer.ASN == asn {
+			return peer
+		}
+	}
+
+	peer := &Peer{IP: ip, ASN: asn}
+	result.updatePeers.Peers = append(result.updatePeers.Peers, peer)
+	return peer
+}
+
+// processBGPUpdate processes the body of a BGP UPDATE message
+func (p *Processor) processBGPUpdate(reader *bytes.Reader, result *Result, peer *Peer, timestamp uint32, asn4, addPath, isMulticast bool) error {
+	// Read withdrawn routes
+	var withdrawnLen uint16
+	if err := binary.Read(reader, binary.BigEndian, &withdrawnLen); err != nil {
+		return err
+	}
+	withdrawnData := make([]byte, withdrawnLen)
+	if _, err := io.ReadFull(reader, withdrawnData); err != nil {
+		return err
+	}
+
+	// Read path attributes
+	var attrLength uint16
+	if err := binary.Read(reader, binary.BigEndian, &attrLength); err != nil {
+		return err
+	}
+	attributes := make([]byte, attrLength)
+	if _, err := io.ReadFull(reader, attributes); err != nil {
+		return err
+	}
+	attrs, err := decodeAttributes(attributes, asn4)
+	if err != nil {
+		return err
+	}
+
+	// The remaining bytes are IPv4 NLRI
+	nlriData := make([]byte, reader.Len())
+	if _, err := io.ReadFull(reader, nlriData); err != nil {
+		return err
+	}
+
+	var updates []Update
+	addUpdates := func(prefixes []nlri, withdraw, isIPv4, isMulticast bool) {
+		for _, n := range prefixes {
+			update := Update{Withdraw: withdraw}
+			if !withdraw {
+				update.Path = attrs.asPath
+			}
+			update.Path.Prefix = n.route
+			update.Path.PathID = n.pathID
+			update.Path.Timestamp = timestamp
+			update.Path.AF = addressFamily(isIPv4, isMulticast)
+			update.Path.Peer = peer
+			updates = append(updates, update)
+		}
+	}
+
+	// IPv4 routes carried in the UPDATE message itself
+	withdrawn, err := decodeNLRI(withdrawnData, true, addPath)
+	if err != nil {
+		return err
+	}
+	addUpdates(withdrawn, true, true, isMulticast)
+
+	if len(attrs.asPath.Path) > 0 {
+		announced, err := decodeNLRI(nlriData, true, addPath)
+		if err != nil {
+			return err
+		}
+		addUpdates(announced, false, true, isMulticast)
+	}
+
+	// Multiprotocol routes
+	if attrs.mpUnreach != nil {
+		isIPv4, mpMulticast, prefixes, err := decodeMPUnreach(attrs.mpUnreach, addPath)
+		if err != nil {
+			return err
+		}
+		addUpdates(prefixes, true, isIPv4, isMulticast || mpMulticast)
+	}
+	if attrs.mpReach != nil && len(attrs.asPath.Path) > 0 {
+		isIPv4, mpMulticast, prefixes, err := decodeMPReach(attrs.mpReach, addPath)
+		if err != nil {
+			return err
+		}
+		addUpdates(prefixes, false, isIPv4, isMulticast || mpMulticast)
+	}
+
+	if len(updates) > 0 {
+		p.Lock()
+		result.Updates = append(result.Updates, updates...)
+		p.Unlock()
+	}
+
+	return nil
+}
+
+// decodeMPReach decodes the MP_REACH_NLRI attribute and returns the address
+// family and announced prefixes. Unsupported AFI/SAFI yield no prefixes.
+func decodeMPReach(data []byte, addPath bool) (bool, bool, []nlri, error) {
+	if len(data) < 5 {
+		return false, false, nil, fmt.Errorf("MP_REACH_NLRI too short")
+	}
+	afi := binary.BigEndian.Uint16(data[0:2])
+	safi := data[2]
+	nextHopLen := int(data[3])
+	if len(data) < 5+nextHopLen {
+		return false, false, nil, fmt.Errorf("MP_REACH_NLRI next hop truncated")
+	}
+	isIPv4, isMulticast, ok := mpFamily(afi, safi)
+	if !ok {
+		return false, false, nil, nil
+	}
+
+	// Skip next hop and reserved byte
+	prefixes, err := decodeNLRI(data[5+nextHopLen:], isIPv4, addPath)
+	return isIPv4, isMulticast, prefixes, err
+}
+
+// decodeMPUnreach decodes the MP_UNREACH_NLRI attribute and returns the
+// address family and withdrawn prefixes
+func decodeMPUnreach(data []byte, addPath bool) (bool, bool, []nlri, error) {
+	if len(data) < 3 {
+		return false, false, nil, fmt.Errorf("MP_UNREACH_NLRI too short")
+	}
+	afi := binary.BigEndian.Uint16(data[0:2])
+	safi := data[2]
+	isIPv4, isMulticast, ok := mpFamily(afi, safi)
+	if !ok {
+		return false, false, nil, nil
+	}
+
+	prefixes, err := decodeNLRI(data[3:], isIPv4, addPath)
+	return isIPv4, isMulticast, prefixes, err
+}
+
+// mpFamily maps AFI/SAFI to IPv4/IPv6 and unicast/multicast
+func mpFamily(afi uint16, safi uint8) (isIPv4, isMulticast, ok bool) {
+	if afi != 1 && afi != 2 {
+		return false, false, false
+	}
+	if safi != 1 && safi != 2 {
+		return false, false, false
+	}
+	return afi == 1, safi == 2, true
+}
+
+// decodeNLRI decodes a sequence of prefixes in NLRI encoding
+func decodeNLRI(data []byte, isIPv4, addPath bool) ([]nlri, error) {
+	var prefixes []nlri
+	maxLen := uint8(128)
+	if isIPv4 {
+		maxLen = 32
+	}
+
+	reader := bytes.NewReader(data)
+	for reader.Len() > 0 {
+		var n nlri
+		if addPath {
+			if err := binary.Read(reader, binary.BigEndian, &n.pathID); err != nil {
+				return nil, err
+			}
+		}
+
+		var prefixLen uint8
+		if err := binary.Read(reader, binary.BigEndian, &prefixLen); err != nil {
+			return nil, err
+		}
+		if prefixLen > maxLen {
+			return nil, fmt.Errorf("invalid NLRI prefix length %d", prefixLen)
+		}
+
+		prefix := make([]byte, (prefixLen+7)/8)
+		if _, err := io.ReadFull(reader, prefix); err != nil {
+			return nil, err
+		}
+		n.route = newRoute(prefix, uint32(prefixLen), isIPv4)
+		prefixes = append(prefixes, n)
+	}
+
+	return prefixes, nil
+}
@@ -0,0 +1,233 @@
+package mrt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// bgp4mpMessage encodes a BGP4MP message record body from an IPv4 session
+func bgp4mpMessage(asn4 bool, peerASN uint32, bgpType uint8, message []byte) []byte {
+	var b []byte
+	if asn4 {
+		b = binary.BigEndian.AppendUint32(b, peerASN)
+		b = binary.BigEndian.AppendUint32(b, 4242420000)
+	} else {
+		b = binary.BigEndian.AppendUint16(b, uint16(peerASN))
+		b = binary.BigEndian.AppendUint16(b, 64512)
+	}
+	b = binary.BigEndian.AppendUint16(b, 0) // Interface index
+	b = binary.BigEndian.AppendUint16(b, 1) // AFI IPv4
+	b = append(b, 172, 20, 0, 1, 172, 20, 0, 254)
+	b = append(b, bytes.Repeat([]byte{0xff}, 16)...)
+	b = binary.BigEndian.AppendUint16(b, uint16(19+len(message)))
+	b = append(b, bgpType)
+	return append(b, message...)
+}
+
+// bgpUpdate encodes the body of a BGP UPDATE message
+func bgpUpdate(withdrawn, attributes, announced []byte) []byte {
+	b := binary.BigEndian.AppendUint16(nil, uint16(len(withdrawn)))
+	b = append(b, withdrawn...)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(attributes)))
+	b = append(b, attributes...)
+	return append(b, announced...)
+}
+
+// prefixNLRI encodes a prefix in NLRI encoding, with a path ID if addPath is set
+func prefixNLRI(addPath bool, pathID uint32, prefixLen uint8, prefix ...byte) []byte {
+	var b []byte
+	if addPath {
+		b = binary.BigEndian.AppendUint32(b, pathID)
+	}
+	return append(append(b, prefixLen), prefix...)
+}
+
+// mpReach encodes an MP_REACH_NLRI attribute with an IPv6 next hop
+func mpReach(afi uint16, safi uint8, nlri []byte) []byte {
+	b := binary.BigEndian.AppendUint16(nil, afi)
+	b = append(b, safi, 16)
+	b = append(b, make([]byte, 16)...)
+	b = append(b, 0)
+	return attribute(14, append(b, nlri...))
+}
+
+// mpUnreach encodes an MP_UNREACH_NLRI attribute
+func mpUnreach(afi uint16, safi uint8, nlri []byte) []byte {
+	b := binary.BigEndian.AppendUint16(nil, afi)
+	b = append(b, safi)
+	return attribute(15, append(b, nlri...))
+}
+
+func TestProcessBGP4MP(t *testing.T) {
+	ipv4 := Route{Length: 24, IPType: "ipv4", IPValue: uint32(0xac140100)}
+	ipv6 := Route{Length: 48, IPType: "ipv6", IPValue: [4]uint32{0xfd424242, 0x00010000, 0, 0}}
+	ipv6Prefix := []byte{0xfd, 0x42, 0x42, 0x42, 0, 1}
+
+	// update is the expected form of an Update, the peer is compared by ASN
+	type update struct {
+		withdraw bool
+		path     []uint32
+		prefix   Route
+		pathID   uint32
+		af       uint32
+		peerASN  uint32
+	}
+
+	tests := []struct {
+		name    string
+		msgType uint16
+		subType uint16
+		body    []byte
+		want    []update
+	}{
+		{
+			name:    "IPv4 announcement",
+			msgType: 16,
+			subType: 4,
+			body: bgp4mpMessage(true, 4242420001, 2, bgpUpdate(nil,
+				sequence(4242420001, 4242420100), prefixNLRI(false, 0, 24, 172, 20, 1))),
+			want: []update{{path: []uint32{4242420001, 4242420100}, prefix: ipv4, af: 1, peerASN: 4242420001}},
+		},
+		{
+			name:    "IPv4 withdrawal",
+			msgType: 16,
+			subType: 4,
+			body: bgp4mpMessage(true, 4242420001, 2, bgpUpdate(
+				prefixNLRI(false, 0, 24, 172, 20, 1), nil, nil)),
+			want: []update{{withdraw: true, prefix: ipv4, af: 1, peerASN: 4242420001}},
+		},
+		{
+			name:    "2-byte ASNs",
+			msgType: 16,
+			subType: 1,
+			body: bgp4mpMessage(false, 64512, 2, bgpUpdate(nil,
+				attribute(2, segment(SegmentASSequence, 2, 64512, 64513)), prefixNLRI(false, 0, 24, 172, 20, 1))),
+			want: []update{{path: []uint32{64512, 64513}, prefix: ipv4, af: 1, peerASN: 64512}},
+		},
+		{
+			name:    "IPv6 MP_REACH_NLRI and MP_UNREACH_NLRI",
+			msgType: 16,
+			subType: 4,
+			body: bgp4mpMessage(true, 4242420001, 2, bgpUpdate(nil, concat(
+				sequence(4242420001, 4242420100),
+				mpUnreach(2, 1, prefixNLRI(false, 0, 24, 0xfd, 0x42, 0x42)),
+				mpReach(2, 1, prefixNLRI(false, 0, 48, ipv6Prefix...)),
+			), nil)),
+			want: []update{
+				{withdraw: true, prefix: Route{Length: 24, IPType: "ipv6", IPValue: [4]uint32{0xfd424200, 0, 0, 0}}, af: 2, peerASN: 4242420001},
+				{path: []uint32{4242420001, 4242420100}, prefix: ipv6, af: 2, peerASN: 4242420001},
+			},
+		},
+		{
+			name:    "IPv6 multicast",
+			msgType: 16,
+			subType: 4,
+			body: bgp4mpMessage(true, 4242420001, 2, bgpUpdate(nil, concat(
+				sequence(4242420001),
+				mpReach(2, 2, prefixNLRI(false, 0, 48, ipv6Prefix...)),
+			), nil)),
+			want: []update{{path: []uint32{4242420001}, prefix: ipv6, af: 8, peerASN: 4242420001}},
+		},
+		{
+			name:    "ADD-PATH",
+			msgType: 16,
+			subType: 9,
+			body: bgp4mpMessage(true, 4242420001, 2, bgpUpdate(
+				prefixNLRI(true, 3, 24, 172, 20, 1),
+				concat(sequence(4242420001, 4242420100), mpReach(2, 1, prefixNLRI(true, 5, 48, ipv6Prefix...))),
+				prefixNLRI(true, 4, 24, 172, 20, 1))),
+			want: []update{
+				{withdraw: true, prefix: ipv4, pathID: 3, af: 1, peerASN: 4242420001},
+				{path: []uint32{4242420001, 4242420100}, prefix: ipv4, pathID: 4, af: 1, peerASN: 4242420001},
+				{path: []uint32{4242420001, 4242420100}, prefix: ipv6, pathID: 5, af: 2, peerASN: 4242420001},
+			},
+		},
+		{
+			name:    "BGP4MP_ET",
+			msgType: 17,
+			subType: 4,
+			body: append([]byte{0, 0, 0, 1}, bgp4mpMessage(true, 4242420001, 2, bgpUpdate(nil,
+				sequence(4242420001), prefixNLRI(false, 0, 24, 172, 20, 1)))...),
+			want: []update{{path: []uint32{4242420001}, prefix: ipv4, af: 1, peerASN: 4242420001}},
+		},
+		{
+			name:    "KEEPALIVE",
+			msgType: 16,
+			subType: 4,
+			body:    bgp4mpMessage(true, 4242420001, 4, nil),
+		},
+		{
+			name:    "state change",
+			msgType: 16,
+			subType: 5,
+			body:    []byte{0, 0, 0, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewProcessor().Process(record(1000, tt.msgType, tt.subType, tt.body), false)
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+			var got []update
+			for _, u := range result.Updates {
+				if u.Path.Timestamp != 1000 {
+					t.Errorf("timestamp = %d, want 1000", u.Path.Timestamp)
+				}
+				if u.Path.Peer == nil {
+					t.Fatalf("update without peer")
+				}
+				got = append(got, update{
+					withdraw: u.Withdraw,
+					path:     u.Path.Path,
+					prefix:   u.Path.Prefix,
+					pathID:   u.Path.PathID,
+					af:       u.Path.AF,
+					peerASN:  u.Path.Peer.ASN,
+				})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("updates = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessBGP4MPSharedPeers(t *testing.T) {
+	announce := bgp4mpMessage(true, 4242420001, 2, bgpUpdate(nil, sequence(4242420001), prefixNLRI(false, 0, 24, 172, 20, 1)))
+	result, err := NewProcessor().Process(concat(record(1000, 16, 4, announce), record(1001, 16, 4, announce)), false)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if len(result.Updates) != 2 || result.Updates[0].Path.Peer != result.Updates[1].Path.Peer {
+		t.Fatalf("updates of one session do not share a peer: %+v", result.Updates)
+	}
+	if len(result.PeerTables) != 1 || len(result.PeerTables[0].Peers) != 1 {
+		t.Errorf("peer tables = %+v, want one table with one peer", result.PeerTables)
+	}
+}
+
+func TestDecodeNLRIInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		isIPv4  bool
+		addPath bool
+	}{
+		{"IPv4 prefix too long", []byte{33, 172, 20, 0, 0, 0}, true, false},
+		{"IPv6 prefix too long", append([]byte{129}, make([]byte, 17)...), false, false},
+		{"truncated prefix", []byte{24, 172, 20}, true, false},
+		{"truncated path ID", []byte{0, 0, 1}, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeNLRI(tt.data, tt.isIPv4, tt.addPath); err == nil {
+				t.Error("decodeNLRI() succeeded, want an error")
+			}
+		})
+	}
+}
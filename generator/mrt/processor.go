@@ -1,25 +1,220 @@
 package mrt
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"net"
 	"sync"
 )
 
+// readBufferSize is the buffer size used when decoding MRT streams
+const readBufferSize = 64 * 1024
+
+// AS_PATH segment types
+const (
+	SegmentASSet            uint8 = 1
+	SegmentASSequence       uint8 = 2
+	SegmentASConfedSequence uint8 = 3
+	SegmentASConfedSet      uint8 = 4
+)
+
+// Path flags, marking AS paths that contain non-sequence segments
+const (
+	PathFlagASSet  uint32 = 1 // Path contains an AS_SET segment
+	PathFlagConfed uint32 = 2 // Path contains an AS_CONFED_SEQUENCE or AS_CONFED_SET segment
+)
+
 // ASPath represents an AS path with address family info
 type ASPath struct {
-	Path []uint32
-	AF   uint32 // Bitmask: 1=IPv4, 2=IPv6
+	Path      []uint32        // All ASNs of the path, flattened across segments
+	Segments  []ASPathSegment // Segments as encoded in the AS_PATH attribute
+	Flags     uint32          // Bitmask of PathFlag* values
+	AF        uint32          // Bitmask: 1=IPv4, 2=IPv6
+	Peer      *Peer           // Feeder peer that reported this path, nil if unknown
+	Prefix    Route           // Prefix this path was announced for
+	PathID    uint32          // ADD-PATH path identifier, 0 if not used
+	Timestamp uint32          // Time the path was announced
+
+	OriginType       uint8            // ORIGIN attribute: 0=IGP, 1=EGP, 2=INCOMPLETE
+	Communities      []uint32         // COMMUNITIES attribute, high 16 bits ASN, low 16 bits value
+	LargeCommunities []LargeCommunity // LARGE_COMMUNITY attribute
+}
+
+// ORIGIN attribute values
+const (
+	OriginIGP        uint8 = 0
+	OriginEGP        uint8 = 1
+	OriginIncomplete uint8 = 2
+)
+
+// LargeCommunity represents a BGP large community (RFC 8092)
+type LargeCommunity struct {
+	GlobalAdmin uint32
+	LocalData1  uint32
+	LocalData2  uint32
+}
+
+// ASPathSegment represents a single AS_PATH segment
+type ASPathSegment struct {
+	Type uint8
+	ASNs []uint32
+}
+
+// SegmentFlag returns the path flag for a segment type, 0 for AS_SEQUENCE
+func SegmentFlag(segType uint8) uint32 {
+	switch segType {
+	case SegmentASSet:
+		return PathFlagASSet
+	case SegmentASConfedSequence, SegmentASConfedSet:
+		return PathFlagConfed
+	}
+	return 0
+}
+
+// Adjacencies returns the AS adjacencies implied by the path. Only neighbours
+// within or across consecutive AS_SEQUENCE segments are adjacent, members of
+// AS_SET and confederation segments have no known order.
+func (a *ASPath) Adjacencies() [][2]uint32 {
+	var adjacencies [][2]uint32
+	var prev uint32
+	hasPrev := false
+
+	for _, seg := range a.Segments {
+		if seg.Type != SegmentASSequence {
+			hasPrev = false
+			continue
+		}
+		for _, asn := range seg.ASNs {
+			if hasPrev {
+				adjacencies = append(adjacencies, [2]uint32{prev, asn})
+			}
+			prev = asn
+			hasPrev = true
+		}
+	}
+
+	return adjacencies
+}
+
+// Origin returns the origin AS of the path. For paths ending with an AS_SET
+// or confederation segment this is the last AS of the preceding AS_SEQUENCE,
+// i.e. the aggregating AS.
+func (a *ASPath) Origin() uint32 {
+	for i := len(a.Segments) - 1; i >= 0; i-- {
+		seg := a.Segments[i]
+		if seg.Type == SegmentASSequence && len(seg.ASNs) > 0 {
+			return seg.ASNs[len(seg.ASNs)-1]
+		}
+	}
+	if len(a.Path) > 0 {
+		return a.Path[len(a.Path)-1]
+	}
+	return 0
+}
+
+// newASPath builds an ASPath from decoded AS_PATH segments
+func newASPath(segments []ASPathSegment) ASPath {
+	asPath := ASPath{Segments: segments}
+	for _, seg := range segments {
+		asPath.Path = append(asPath.Path, seg.ASNs...)
+		asPath.Flags |= SegmentFlag(seg.Type)
+	}
+	return asPath
+}
+
+// decodeASPath decodes the segments of an AS_PATH attribute with 2- or 4-byte ASNs
+func decodeASPath(data []byte, asnSize int) []ASPathSegment {
+	var segments []ASPathSegment
+
+	reader := bytes.NewReader(data)
+	for reader.Len() > 0 {
+		var segType uint8
+		var segLength uint8
+		if err := binary.Read(reader, binary.BigEndian, &segType); err != nil {
+			break
+		}
+		if err := binary.Read(reader, binary.BigEndian, &segLength); err != nil {
+			break
+		}
+
+		// Read AS numbers
+		seg := ASPathSegment{Type: segType, ASNs: make([]uint32, 0, segLength)}
+		for i := uint8(0); i < segLength; i++ {
+			var asn uint32
+			if asnSize == 2 {
+				var asn16 uint16
+				if err := binary.Read(reader, binary.BigEndian, &asn16); err != nil {
+					break
+				}
+				asn = uint32(asn16)
+			} else if err := binary.Read(reader, binary.BigEndian, &asn); err != nil {
+				break
+			}
+			seg.ASNs = append(seg.ASNs, asn)
+		}
+		segments = append(segments, seg)
+	}
+
+	return segments
+}
+
+// mergeAS4Path reconstructs the 4-byte AS path from AS_PATH and AS4_PATH as
+// described in RFC 6793, keeping the leading ASNs that only AS_PATH carries.
+func mergeAS4Path(asPath, as4Path []ASPathSegment) []ASPathSegment {
+	countASNs := func(segments []ASPathSegment) int {
+		n := 0
+		for _, seg := range segments {
+			n += len(seg.ASNs)
+		}
+		return n
+	}
+
+	leading := countASNs(asPath) - countASNs(as4Path)
+	if leading < 0 {
+		return asPath
+	}
+
+	var merged []ASPathSegment
+	for _, seg := range asPath {
+		if leading == 0 {
+			break
+		}
+		n := min(leading, len(seg.ASNs))
+		merged = append(merged, ASPathSegment{Type: seg.Type, ASNs: seg.ASNs[:n]})
+		leading -= n
+	}
+
+	return append(merged, as4Path...)
+}
+
+// Peer represents a peer entry of a TABLE_DUMP_V2 PEER_INDEX_TABLE
+type Peer struct {
+	BGPID string
+	IP    string
+	ASN   uint32
+}
+
+// PeerIndexTable represents a TABLE_DUMP_V2 PEER_INDEX_TABLE
+type PeerIndexTable struct {
+	CollectorBGPID string
+	ViewName       string
+	Peers          []*Peer
 }
 
 // Result stores the results of MRT processing
 type Result struct {
-	ASPaths             []ASPath           // AS path list
-	Advertises          map[uint32][]Route // ASN to unicast route mapping
-	AdvertisesMulticast map[uint32][]Route // ASN to multicast route mapping
+	ASPaths             []ASPath             // AS path list
+	Advertises          map[uint32][]Route   // ASN to unicast route mapping
+	AdvertisesMulticast map[uint32][]Route   // ASN to multicast route mapping
+	PeerTables          []*PeerIndexTable    // Peer index tables, the last one applies to subsequent RIB entries
+	Updates             []Update             // Announcements and withdrawals from BGP4MP update streams
+	Withdrawals         map[[2]uint32]uint32 // AS adjacency to the time it was last withdrawn, set by RIB
 	Metadata            *Metadata
+
+	updatePeers *PeerIndexTable // Peers of BGP4MP sessions seen in this stream
 }
 
 // Route represents a route entry
@@ -44,20 +239,32 @@ func NewProcessor() *Processor {
 	return &Processor{}
 }
 
-// Process processes MRT data and returns the result.
+// Process processes an in-memory MRT dump and returns the result.
 // isMulticast indicates whether this data came from a multicast MRT dump URL.
 func (p *Processor) Process(data []byte, isMulticast bool) (*Result, error) {
+	return p.ProcessReader(bytes.NewReader(data), isMulticast)
+}
+
+// ProcessReader decodes MRT records one by one from r and returns the result.
+// Only one record body is held in memory at a time, so r can be a
+// decompressing stream of arbitrary size.
+// isMulticast indicates whether this data came from a multicast MRT dump URL.
+func (p *Processor) ProcessReader(r io.Reader, isMulticast bool) (*Result, error) {
 	result := &Result{
 		ASPaths:             make([]ASPath, 0),
 		Advertises:          make(map[uint32][]Route),
 		AdvertisesMulticast: make(map[uint32][]Route),
 	}
 
-	reader := bytes.NewReader(data)
-	for reader.Len() > 0 {
-		// Read MRT header
-		header := make([]byte, 12)
-		if _, err := reader.Read(header); err != nil {
+	reader := bufio.NewReaderSize(r, readBufferSize)
+	header := make([]byte, 12)
+	var body []byte
+	for {
+		// Read MRT header, a clean EOF here means the stream is complete
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if err == io.EOF {
+				break
+			}
 			return nil, fmt.Errorf("failed to read MRT header: %v", err)
 		}
 
@@ -66,9 +273,12 @@ func (p *Processor) Process(data []byte, isMulticast bool) (*Result, error) {
 		subType := binary.BigEndian.Uint16(header[6:8])
 		length := binary.BigEndian.Uint32(header[8:12])
 
-		// Read message body
-		body := make([]byte, length)
-		if _, err := reader.Read(body); err != nil {
+		// Read message body, reusing the buffer between records
+		if uint32(cap(body)) < length {
+			body = make([]byte, length)
+		}
+		body = body[:length]
+		if _, err := io.ReadFull(reader, body); err != nil {
 			return nil, fmt.Errorf("failed to read MRT body: %v", err)
 		}
 
@@ -78,6 +288,17 @@ func (p *Processor) Process(data []byte, isMulticast bool) (*Result, error) {
 			if err := p.processTableDumpV2(subType, body, result, isMulticast); err != nil {
 				return nil, err
 			}
+		case 16: // BGP4MP
+			if err := p.processBGP4MP(subType, body, timestamp, result, isMulticast); err != nil {
+				return nil, err
+			}
+		case 17: // BGP4MP_ET, the body starts with the microsecond timestamp
+			if len(body) < 4 {
+				return nil, fmt.Errorf("BGP4MP_ET record too short")
+			}
+			if err := p.processBGP4MP(subType, body[4:], timestamp, result, isMulticast); err != nil {
+				return nil, err
+			}
 		}
 
 		// Set metadata
@@ -86,9 +307,6 @@ func (p *Processor) Process(data []byte, isMulticast bool) (*Result, error) {
 				Timestamp: uint64(timestamp),
 			}
 		}
-
-		// Help GC by clearing the body after processing
-		body = nil
 	}
 
 	return result, nil
@@ -102,8 +320,7 @@ func (p *Processor) processTableDumpV2(subType uint16, data []byte, result *Resu
 
 	switch subType {
 	case 1: // PEER_INDEX_TABLE
-		// Skip peer index table
-		return nil
+		return p.processPeerIndexTable(reader, result)
 	case 2: // RIB_IPV4_UNICAST
 		fallthrough
 	case 3: // RIB_IPV4_MULTICAST
@@ -125,6 +342,93 @@ func (p *Processor) processTableDumpV2(subType uint16, data []byte, result *Resu
 	return nil
 }
 
+// processPeerIndexTable processes the PEER_INDEX_TABLE, which maps the peer
+// indexes referenced by RIB entries to the peers of the collector
+func (p *Processor) processPeerIndexTable(reader *bytes.Reader, result *Result) error {
+	table := &PeerIndexTable{}
+
+	// Read collector BGP ID
+	collectorBGPID := make([]byte, 4)
+	if _, err := io.ReadFull(reader, collectorBGPID); err != nil {
+		return err
+	}
+	table.CollectorBGPID = net.IP(collectorBGPID).String()
+
+	// Read view name
+	var viewNameLen uint16
+	if err := binary.Read(reader, binary.BigEndian, &viewNameLen); err != nil {
+		return err
+	}
+	viewName := make([]byte, viewNameLen)
+	if _, err := io.ReadFull(reader, viewName); err != nil {
+		return err
+	}
+	table.ViewName = string(viewName)
+
+	// Read peer entries
+	var peerCount uint16
+	if err := binary.Read(reader, binary.BigEndian, &peerCount); err != nil {
+		return err
+	}
+
+	table.Peers = make([]*Peer, 0, peerCount)
+	for i := uint16(0); i < peerCount; i++ {
+		// Peer type bit 0: IPv6 peer address, bit 1: 4-byte peer AS
+		var peerType uint8
+		if err := binary.Read(reader, binary.BigEndian, &peerType); err != nil {
+			return err
+		}
+
+		bgpID := make([]byte, 4)
+		if _, err := io.ReadFull(reader, bgpID); err != nil {
+			return err
+		}
+
+		ipLen := 4
+		if peerType&0x01 != 0 {
+			ipLen = 16
+		}
+		ip := make([]byte, ipLen)
+		if _, err := io.ReadFull(reader, ip); err != nil {
+			return err
+		}
+
+		var asn uint32
+		if peerType&0x02 != 0 {
+			if err := binary.Read(reader, binary.BigEndian, &asn); err != nil {
+				return err
+			}
+		} else {
+			var asn16 uint16
+			if err := binary.Read(reader, binary.BigEndian, &asn16); err != nil {
+				return err
+			}
+			asn = uint32(asn16)
+		}
+
+		table.Peers = append(table.Peers, &Peer{
+			BGPID: net.IP(bgpID).String(),
+			IP:    net.IP(ip).String(),
+			ASN:   asn,
+		})
+	}
+
+	result.PeerTables = append(result.PeerTables, table)
+	return nil
+}
+
+// lookupPeer resolves a RIB entry peer index against the current peer index table
+func lookupPeer(result *Result, peerIndex uint16) *Peer {
+	if len(result.PeerTables) == 0 {
+		return nil
+	}
+	peers := result.PeerTables[len(result.PeerTables)-1].Peers
+	if int(peerIndex) >= len(peers) {
+		return nil
+	}
+	return peers[peerIndex]
+}
+
 // processRIBEntry processes RIB entries
 func (p *Processor) processRIBEntry(subType uint16, reader *bytes.Reader, result *Result, isMulticast bool) error {
 	// Read sequence number (skip)
@@ -142,27 +446,13 @@ func (p *Processor) processRIBEntry(subType uint16, reader *bytes.Reader, result
 	// Calculate prefix bytes
 	prefixBytes := (prefixLen + 7) / 8
 	prefix := make([]byte, prefixBytes)
-	if _, err := reader.Read(prefix); err != nil {
+	if _, err := io.ReadFull(reader, prefix); err != nil {
 		return err
 	}
 
-	// Parse IP address
-	var ipStr string
-	if subType == 2 || subType == 3 || subType == 8 || subType == 9 { // IPv4 (unicast/multicast)
-		if prefixBytes < 4 {
-			// Extend the slice in-place instead of creating a new one
-			prefix = append(prefix, make([]byte, 4-prefixBytes)...)
-		}
-		ip := net.IPv4(prefix[0], prefix[1], prefix[2], prefix[3])
-		ipStr = ip.String()
-	} else { // IPv6 (type 4, 5, 10, 11)
-		if prefixBytes < 16 {
-			// Extend the slice in-place
-			prefix = append(prefix, make([]byte, 16-prefixBytes)...)
-		}
-		ip := net.IP(prefix)
-		ipStr = ip.String()
-	}
+	// Parse IP address, IPv4 for (unicast/multicast) types 2, 3, 8, 9, otherwise IPv6 (type 4, 5, 10, 11)
+	isIPv4 := subType == 2 || subType == 3 || subType == 8 || subType == 9
+	route := newRoute(prefix, uint32(prefixLen), isIPv4)
 
 	// Read entry count
 	var entryCount uint16
@@ -170,17 +460,12 @@ func (p *Processor) processRIBEntry(subType uint16, reader *bytes.Reader, result
 		return err
 	}
 
+	// ADDPATH types 8-11 carry a path identifier in every entry
+	addPath := subType == 8 || subType == 9 || subType == 10 || subType == 11
+
 	// Process each entry
 	for i := uint16(0); i < entryCount; i++ {
-		// If ADDPATH type, read path identifier
-		if subType == 8 || subType == 9 || subType == 10 || subType == 11 {
-			var pathID uint32
-			if err := binary.Read(reader, binary.BigEndian, &pathID); err != nil {
-				return err
-			}
-		}
-
-		if err := p.processRIBEntryDescriptor(reader, result, ipStr, uint32(prefixLen), subType, isMulticast); err != nil {
+		if err := p.processRIBEntryDescriptor(reader, result, route, addPath, isIPv4, isMulticast); err != nil {
 			return err
 		}
 	}
@@ -189,19 +474,27 @@ func (p *Processor) processRIBEntry(subType uint16, reader *bytes.Reader, result
 }
 
 // processRIBEntryDescriptor processes RIB entry descriptors
-func (p *Processor) processRIBEntryDescriptor(reader *bytes.Reader, result *Result, prefix string, prefixLen uint32, subType uint16, isMulticast bool) error {
-	// Skip peer index
+func (p *Processor) processRIBEntryDescriptor(reader *bytes.Reader, result *Result, route Route, addPath, isIPv4, isMulticast bool) error {
+	// Read peer index
 	var peerIndex uint16
 	if err := binary.Read(reader, binary.BigEndian, &peerIndex); err != nil {
 		return err
 	}
 
-	// Skip timestamp
+	// Read originated time, the time this path was last announced
 	var originatedTime uint32
 	if err := binary.Read(reader, binary.BigEndian, &originatedTime); err != nil {
 		return err
 	}
 
+	// If ADDPATH type, read path identifier, which follows the originated time (RFC 8050)
+	var pathID uint32
+	if addPath {
+		if err := binary.Read(reader, binary.BigEndian, &pathID); err != nil {
+			return err
+		}
+	}
+
 	// Read attribute length
 	var attrLength uint16
 	if err := binary.Read(reader, binary.BigEndian, &attrLength); err != nil {
@@ -210,23 +503,59 @@ func (p *Processor) processRIBEntryDescriptor(reader *bytes.Reader, result *Resu
 
 	// Read attributes
 	attributes := make([]byte, attrLength)
-	if _, err := reader.Read(attributes); err != nil {
+	if _, err := io.ReadFull(reader, attributes); err != nil {
+		return err
+	}
+
+	// Process attributes, TABLE_DUMP_V2 always encodes 4-byte ASNs
+	attrs, err := decodeAttributes(attributes, true)
+	if err != nil {
 		return err
 	}
 
-	// Process attributes
-	attrReader := bytes.NewReader(attributes)
-	var asPath []uint32
-	var lastAS uint32
+	// If AS path is found, add to result
+	if len(attrs.asPath.Path) > 0 {
+		asPath := attrs.asPath
+		asPath.Prefix = route
+		asPath.PathID = pathID
+		asPath.Timestamp = originatedTime
+		asPath.AF = addressFamily(isIPv4, isMulticast)
+		asPath.Peer = lookupPeer(result, peerIndex)
+
+		p.Lock()
+		result.ASPaths = append(result.ASPaths, asPath)
+		addAdvertise(result, &asPath)
+		p.Unlock()
+	}
+
+	return nil
+}
+
+// pathAttributes holds the decoded BGP path attributes of a route
+type pathAttributes struct {
+	asPath           ASPath // AS path carrying the origin and community attributes below
+	origin           uint8
+	communities      []uint32
+	largeCommunities []LargeCommunity
+	mpReach          []byte // MP_REACH_NLRI attribute value
+	mpUnreach        []byte // MP_UNREACH_NLRI attribute value
+}
+
+// decodeAttributes decodes BGP path attributes. asn4 indicates whether
+// AS_PATH carries 4-byte ASNs, otherwise AS4_PATH is merged into it.
+func decodeAttributes(data []byte, asn4 bool) (*pathAttributes, error) {
+	attrs := &pathAttributes{}
+	var asPath, as4Path []ASPathSegment
 
+	attrReader := bytes.NewReader(data)
 	for attrReader.Len() > 0 {
 		var flags uint8
 		var typeCode uint8
 		if err := binary.Read(attrReader, binary.BigEndian, &flags); err != nil {
-			return err
+			return nil, err
 		}
 		if err := binary.Read(attrReader, binary.BigEndian, &typeCode); err != nil {
-			return err
+			return nil, err
 		}
 
 		// Read length
@@ -234,134 +563,131 @@ func (p *Processor) processRIBEntryDescriptor(reader *bytes.Reader, result *Resu
 		if (flags & 0x10) != 0 {
 			// Extended length
 			if err := binary.Read(attrReader, binary.BigEndian, &length); err != nil {
-				return err
+				return nil, err
 			}
 		} else {
 			var l uint8
 			if err := binary.Read(attrReader, binary.BigEndian, &l); err != nil {
-				return err
+				return nil, err
 			}
 			length = uint16(l)
 		}
 
-		// Process AS_PATH attribute
-		if typeCode == 2 {
-			asPath = make([]uint32, 0)
-			asPathData := make([]byte, length)
-			if _, err := attrReader.Read(asPathData); err != nil {
-				return err
-			}
-
-			asPathReader := bytes.NewReader(asPathData)
-			for asPathReader.Len() > 0 {
-				var segType uint8
-				var segLength uint8
-				if err := binary.Read(asPathReader, binary.BigEndian, &segType); err != nil {
-					break
-				}
-				if err := binary.Read(asPathReader, binary.BigEndian, &segLength); err != nil {
-					break
-				}
+		value := make([]byte, length)
+		if _, err := io.ReadFull(attrReader, value); err != nil {
+			return nil, err
+		}
 
-				// Read AS number
-				for i := uint8(0); i < segLength; i++ {
-					var asn uint32
-					if err := binary.Read(asPathReader, binary.BigEndian, &asn); err != nil {
-						break
-					}
-					asPath = append(asPath, asn)
-				}
+		switch typeCode {
+		case 1: // ORIGIN
+			if len(value) > 0 {
+				attrs.origin = value[0]
 			}
-
-			if len(asPath) > 0 {
-				lastAS = asPath[len(asPath)-1]
+		case 2: // AS_PATH
+			asnSize := 2
+			if asn4 {
+				asnSize = 4
 			}
-
-			// Help GC
-			asPathData = nil
-		} else {
-			// Skip other attributes
-			if _, err := attrReader.Seek(int64(length), 1); err != nil {
-				return err
+			asPath = decodeASPath(value, asnSize)
+		case 8: // COMMUNITIES
+			for i := 0; i+4 <= len(value); i += 4 {
+				attrs.communities = append(attrs.communities, binary.BigEndian.Uint32(value[i:i+4]))
+			}
+		case 14: // MP_REACH_NLRI
+			attrs.mpReach = value
+		case 15: // MP_UNREACH_NLRI
+			attrs.mpUnreach = value
+		case 17: // AS4_PATH
+			as4Path = decodeASPath(value, 4)
+		case 32: // LARGE_COMMUNITY
+			for i := 0; i+12 <= len(value); i += 12 {
+				attrs.largeCommunities = append(attrs.largeCommunities, LargeCommunity{
+					GlobalAdmin: binary.BigEndian.Uint32(value[i : i+4]),
+					LocalData1:  binary.BigEndian.Uint32(value[i+4 : i+8]),
+					LocalData2:  binary.BigEndian.Uint32(value[i+8 : i+12]),
+				})
 			}
 		}
 	}
 
-	// If AS path is found, add to result
-	if len(asPath) > 0 {
-		p.Lock()
-		// AF bitmask: 1=IPv4 unicast, 2=IPv6 unicast, 4=IPv4 multicast, 8=IPv6 multicast
-		// Determine IPv4 vs IPv6 from subtype; unicast vs multicast from collector source
-		isIPv4 := subType == 2 || subType == 3 || subType == 8 || subType == 9 // Otherwise IPv6 (type 4, 5, 10, 11)
-		var af uint32
-		if isMulticast {
-			if isIPv4 {
-				af = 4 // 0100 - IPv4 multicast
-			} else {
-				af = 8 // 1000 - IPv6 multicast
-			}
-		} else {
-			if isIPv4 {
-				af = 1 // 0001 - IPv4 unicast
-			} else {
-				af = 2 // 0010 - IPv6 unicast
-			}
-		}
-		result.ASPaths = append(result.ASPaths, ASPath{Path: asPath, AF: af})
+	if !asn4 && as4Path != nil {
+		asPath = mergeAS4Path(asPath, as4Path)
+	}
+	attrs.asPath = newASPath(asPath)
+	attrs.asPath.OriginType = attrs.origin
+	attrs.asPath.Communities = attrs.communities
+	attrs.asPath.LargeCommunities = attrs.largeCommunities
 
-		// Create route entry
-		route := Route{
-			Length: prefixLen,
-		}
+	return attrs, nil
+}
 
+// addressFamily returns the AF bitmask of a route:
+// 1=IPv4 unicast, 2=IPv6 unicast, 4=IPv4 multicast, 8=IPv6 multicast
+func addressFamily(isIPv4, isMulticast bool) uint32 {
+	if isMulticast {
 		if isIPv4 {
-			route.IPType = "ipv4"
-			ip := net.ParseIP(prefix).To4()
-			if ip != nil {
-				route.IPValue = binary.BigEndian.Uint32(ip)
-			}
-		} else { // IPv6
-			route.IPType = "ipv6"
-			ip := net.ParseIP(prefix).To16()
-			if ip != nil {
-				high := binary.BigEndian.Uint64(ip[:8])
-				low := binary.BigEndian.Uint64(ip[8:])
-				route.IPValue = [4]uint32{
-					uint32(high >> 32),
-					uint32(high),
-					uint32(low >> 32),
-					uint32(low),
-				}
-			}
+			return 4 // 0100 - IPv4 multicast
 		}
+		return 8 // 1000 - IPv6 multicast
+	}
+	if isIPv4 {
+		return 1 // 0001 - IPv4 unicast
+	}
+	return 2 // 0010 - IPv6 unicast
+}
 
-		// Determine target map based on collector source
-		targetMap := result.Advertises
-		if isMulticast {
-			targetMap = result.AdvertisesMulticast
-		}
+// isMulticastAF reports whether the AF bitmask denotes a multicast route
+func isMulticastAF(af uint32) bool {
+	return af&(4|8) != 0
+}
 
-		// Check for duplicates
-		routes := targetMap[lastAS]
-		isDuplicate := false
-		for _, r := range routes {
-			if r.Length == route.Length && r.IPType == route.IPType && r.IPValue == route.IPValue {
-				isDuplicate = true
-				break
-			}
-		}
+// newRoute creates a route entry from the (possibly truncated) prefix bytes
+func newRoute(prefix []byte, prefixLen uint32, isIPv4 bool) Route {
+	route := Route{
+		Length: prefixLen,
+	}
 
-		// Only append if it's a new route
-		if !isDuplicate {
-			targetMap[lastAS] = append(targetMap[lastAS], route)
+	if isIPv4 {
+		ip := make([]byte, 4)
+		copy(ip, prefix)
+		route.IPType = "ipv4"
+		route.IPValue = binary.BigEndian.Uint32(ip)
+	} else {
+		ip := make([]byte, 16)
+		copy(ip, prefix)
+		route.IPType = "ipv6"
+		route.IPValue = [4]uint32{
+			binary.BigEndian.Uint32(ip[0:4]),
+			binary.BigEndian.Uint32(ip[4:8]),
+			binary.BigEndian.Uint32(ip[8:12]),
+			binary.BigEndian.Uint32(ip[12:16]),
 		}
-		p.Unlock()
 	}
 
-	// Help GC
-	attributes = nil
+	return route
+}
 
-	return nil
+// addAdvertise records the prefix of the path as advertised by its origin AS
+func addAdvertise(result *Result, asPath *ASPath) {
+	origin := asPath.Origin()
+	route := asPath.Prefix
+
+	// Determine target map based on address family
+	targetMap := result.Advertises
+	if isMulticastAF(asPath.AF) {
+		targetMap = result.AdvertisesMulticast
+	}
+
+	// Check for duplicates
+	routes := targetMap[origin]
+	for _, r := range routes {
+		if r.Length == route.Length && r.IPType == route.IPType && r.IPValue == route.IPValue {
+			return
+		}
+	}
+
+	// Only append if it's a new route
+	targetMap[origin] = append(targetMap[origin], route)
 }
 
 // MergeResults merges multiple processing results
@@ -377,8 +703,10 @@ func MergeResults(results chan *Result) *Result {
 			continue
 		}
 
-		// Append AS paths
+		// Append AS paths and the peer tables they refer to
 		merged.ASPaths = append(merged.ASPaths, result.ASPaths...)
+		merged.PeerTables = append(merged.PeerTables, result.PeerTables...)
+		merged.Updates = append(merged.Updates, result.Updates...)
 
 		// Merge advertised routes
 		for asn, routes := range result.Advertises {
@@ -398,6 +726,8 @@ func MergeResults(results chan *Result) *Result {
 		result.ASPaths = nil
 		result.Advertises = nil
 		result.AdvertisesMulticast = nil
+		result.PeerTables = nil
+		result.Updates = nil
 	}
 
 	return merged
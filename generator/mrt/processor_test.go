@@ -0,0 +1,384 @@
+package mrt
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// record encodes an MRT record
+func record(timestamp uint32, msgType, subType uint16, body []byte) []byte {
+	b := binary.BigEndian.AppendUint32(nil, timestamp)
+	b = binary.BigEndian.AppendUint16(b, msgType)
+	b = binary.BigEndian.AppendUint16(b, subType)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(body)))
+	return append(b, body...)
+}
+
+// attribute encodes a transitive path attribute, with extended length if needed
+func attribute(typeCode uint8, value []byte) []byte {
+	if len(value) > 255 {
+		b := []byte{0x50, typeCode}
+		b = binary.BigEndian.AppendUint16(b, uint16(len(value)))
+		return append(b, value...)
+	}
+	return append([]byte{0x40, typeCode, uint8(len(value))}, value...)
+}
+
+// segment encodes an AS_PATH segment with 2- or 4-byte ASNs
+func segment(segType uint8, asnSize int, asns ...uint32) []byte {
+	b := []byte{segType, uint8(len(asns))}
+	for _, asn := range asns {
+		if asnSize == 2 {
+			b = binary.BigEndian.AppendUint16(b, uint16(asn))
+		} else {
+			b = binary.BigEndian.AppendUint32(b, asn)
+		}
+	}
+	return b
+}
+
+// concat joins byte slices
+func concat(parts ...[]byte) []byte {
+	var b []byte
+	for _, part := range parts {
+		b = append(b, part...)
+	}
+	return b
+}
+
+// peerIndexTable encodes a PEER_INDEX_TABLE of IPv4 peers with 4-byte ASNs
+func peerIndexTable(viewName string, peers ...Peer) []byte {
+	b := []byte{192, 0, 2, 1}
+	b = binary.BigEndian.AppendUint16(b, uint16(len(viewName)))
+	b = append(b, viewName...)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(peers)))
+	for i, peer := range peers {
+		b = append(b, 0x02, 10, 0, 0, uint8(i+1), 172, 20, 0, uint8(i+1))
+		b = binary.BigEndian.AppendUint32(b, peer.ASN)
+	}
+	return b
+}
+
+// ribEntry is a RIB entry of a fixture
+type ribEntry struct {
+	peerIndex      uint16
+	originatedTime uint32
+	pathID         uint32
+	attributes     []byte
+}
+
+// ribRecord encodes a RIB record body, ADD-PATH subtypes carry the path IDs
+func ribRecord(subType uint16, prefix []byte, prefixLen uint8, entries ...ribEntry) []byte {
+	addPath := subType >= 8
+	b := binary.BigEndian.AppendUint32(nil, 1) // Sequence number
+	b = append(b, prefixLen)
+	b = append(b, prefix...)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(entries)))
+	for _, e := range entries {
+		b = binary.BigEndian.AppendUint16(b, e.peerIndex)
+		b = binary.BigEndian.AppendUint32(b, e.originatedTime)
+		if addPath {
+			b = binary.BigEndian.AppendUint32(b, e.pathID)
+		}
+		b = binary.BigEndian.AppendUint16(b, uint16(len(e.attributes)))
+		b = append(b, e.attributes...)
+	}
+	return b
+}
+
+// sequence returns the attributes of an IGP route over an AS_SEQUENCE
+func sequence(asns ...uint32) []byte {
+	return concat(attribute(1, []byte{OriginIGP}), attribute(2, segment(SegmentASSequence, 4, asns...)))
+}
+
+func TestProcessTableDumpV2(t *testing.T) {
+	peers := []Peer{{ASN: 4242420001}, {ASN: 4242420002}}
+	ipv4 := Route{Length: 24, IPType: "ipv4", IPValue: uint32(0xac140100)}
+	ipv6 := Route{Length: 48, IPType: "ipv6", IPValue: [4]uint32{0xfd424242, 0x00010000, 0, 0}}
+
+	tests := []struct {
+		name    string
+		subType uint16
+		body    []byte
+		want    []ASPath // Only Path, Prefix, PathID, Timestamp, AF and Peer.ASN are compared
+	}{
+		{
+			name:    "IPv4 unicast",
+			subType: 2,
+			body: ribRecord(2, []byte{172, 20, 1}, 24,
+				ribEntry{peerIndex: 0, originatedTime: 1000, attributes: sequence(4242420001, 4242420100)},
+				ribEntry{peerIndex: 1, originatedTime: 2000, attributes: sequence(4242420002, 4242420100)}),
+			want: []ASPath{
+				{Path: []uint32{4242420001, 4242420100}, Prefix: ipv4, Timestamp: 1000, AF: 1, Peer: &peers[0]},
+				{Path: []uint32{4242420002, 4242420100}, Prefix: ipv4, Timestamp: 2000, AF: 1, Peer: &peers[1]},
+			},
+		},
+		{
+			name:    "IPv4 multicast",
+			subType: 3,
+			body: ribRecord(3, []byte{172, 20, 1}, 24,
+				ribEntry{peerIndex: 1, originatedTime: 1000, attributes: sequence(4242420002)}),
+			want: []ASPath{
+				{Path: []uint32{4242420002}, Prefix: ipv4, Timestamp: 1000, AF: 4, Peer: &peers[1]},
+			},
+		},
+		{
+			name:    "IPv6 unicast",
+			subType: 4,
+			body: ribRecord(4, []byte{0xfd, 0x42, 0x42, 0x42, 0, 1}, 48,
+				ribEntry{peerIndex: 0, originatedTime: 1000, attributes: sequence(4242420001, 4242420100)}),
+			want: []ASPath{
+				{Path: []uint32{4242420001, 4242420100}, Prefix: ipv6, Timestamp: 1000, AF: 2, Peer: &peers[0]},
+			},
+		},
+		{
+			name:    "IPv4 unicast ADD-PATH",
+			subType: 8,
+			body: ribRecord(8, []byte{172, 20, 1}, 24,
+				ribEntry{peerIndex: 1, originatedTime: 1000, pathID: 7, attributes: sequence(4242420002, 4242420100)},
+				ribEntry{peerIndex: 1, originatedTime: 3000, pathID: 9, attributes: sequence(4242420002, 4242420001, 4242420100)}),
+			want: []ASPath{
+				{Path: []uint32{4242420002, 4242420100}, Prefix: ipv4, PathID: 7, Timestamp: 1000, AF: 1, Peer: &peers[1]},
+				{Path: []uint32{4242420002, 4242420001, 4242420100}, Prefix: ipv4, PathID: 9, Timestamp: 3000, AF: 1, Peer: &peers[1]},
+			},
+		},
+		{
+			name:    "IPv6 multicast ADD-PATH",
+			subType: 11,
+			body: ribRecord(11, []byte{0xfd, 0x42, 0x42, 0x42, 0, 1}, 48,
+				ribEntry{peerIndex: 0, originatedTime: 1000, pathID: 1, attributes: sequence(4242420001)}),
+			want: []ASPath{
+				{Path: []uint32{4242420001}, Prefix: ipv6, PathID: 1, Timestamp: 1000, AF: 8, Peer: &peers[0]},
+			},
+		},
+		{
+			name:    "unknown peer index",
+			subType: 2,
+			body: ribRecord(2, []byte{172, 20, 1}, 24,
+				ribEntry{peerIndex: 5, originatedTime: 1000, attributes: sequence(4242420001)}),
+			want: []ASPath{
+				{Path: []uint32{4242420001}, Prefix: ipv4, Timestamp: 1000, AF: 1},
+			},
+		},
+		{
+			name:    "entry without AS path",
+			subType: 2,
+			body: ribRecord(2, []byte{172, 20, 1}, 24,
+				ribEntry{peerIndex: 0, originatedTime: 1000, attributes: attribute(1, []byte{OriginIGP})}),
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dump := concat(
+				record(500, 13, 1, peerIndexTable("dn42", peers...)),
+				record(600, 13, tt.subType, tt.body),
+			)
+			result, err := NewProcessor().Process(dump, false)
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+			if len(result.ASPaths) != len(tt.want) {
+				t.Fatalf("got %d paths, want %d", len(result.ASPaths), len(tt.want))
+			}
+			for i, got := range result.ASPaths {
+				want := tt.want[i]
+				if !reflect.DeepEqual(got.Path, want.Path) || got.Prefix != want.Prefix || got.PathID != want.PathID ||
+					got.Timestamp != want.Timestamp || got.AF != want.AF {
+					t.Errorf("path %d = %v %v id %d time %d af %d, want %v %v id %d time %d af %d", i,
+						got.Path, got.Prefix, got.PathID, got.Timestamp, got.AF,
+						want.Path, want.Prefix, want.PathID, want.Timestamp, want.AF)
+				}
+				switch {
+				case want.Peer == nil && got.Peer != nil:
+					t.Errorf("path %d peer = AS%d, want none", i, got.Peer.ASN)
+				case want.Peer != nil && (got.Peer == nil || got.Peer.ASN != want.Peer.ASN):
+					t.Errorf("path %d peer = %v, want AS%d", i, got.Peer, want.Peer.ASN)
+				}
+			}
+			if result.Metadata == nil || result.Metadata.Timestamp != 500 {
+				t.Errorf("metadata = %v, want timestamp 500", result.Metadata)
+			}
+		})
+	}
+}
+
+func TestProcessPeerIndexTable(t *testing.T) {
+	result, err := NewProcessor().Process(record(500, 13, 1, peerIndexTable("dn42", Peer{ASN: 4242420001})), false)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	want := []*PeerIndexTable{{
+		CollectorBGPID: "192.0.2.1",
+		ViewName:       "dn42",
+		Peers:          []*Peer{{BGPID: "10.0.0.1", IP: "172.20.0.1", ASN: 4242420001}},
+	}}
+	if !reflect.DeepEqual(result.PeerTables, want) {
+		t.Errorf("PeerTables = %+v, want %+v", result.PeerTables[0], want[0])
+	}
+}
+
+func TestProcessMalformed(t *testing.T) {
+	rib := ribRecord(2, []byte{172, 20, 1}, 24, ribEntry{attributes: sequence(4242420001)})
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated header", []byte{0, 0, 0, 1, 0, 13}},
+		{"truncated body", record(500, 13, 2, rib)[:20]},
+		{"truncated RIB entry", record(500, 13, 2, rib[:len(rib)-3])},
+		{"truncated attribute", record(500, 13, 2, ribRecord(2, []byte{172, 20, 1}, 24,
+			ribEntry{attributes: []byte{0x40, 2, 10, 2, 1}}))},
+		{"truncated BGP4MP_ET", record(500, 17, 4, []byte{0, 0})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewProcessor().Process(tt.data, false); err == nil {
+				t.Error("Process() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestDecodeAttributes(t *testing.T) {
+	longSet := make([]uint32, 70)
+	for i := range longSet {
+		longSet[i] = 4242420000 + uint32(i)
+	}
+
+	tests := []struct {
+		name             string
+		data             []byte
+		asn4             bool
+		segments         []ASPathSegment
+		flags            uint32
+		origin           uint8
+		communities      []uint32
+		largeCommunities []LargeCommunity
+		adjacencies      [][2]uint32
+	}{
+		{
+			name:        "4-byte AS_SEQUENCE",
+			data:        concat(attribute(1, []byte{OriginEGP}), attribute(2, segment(SegmentASSequence, 4, 4242420001, 4242420002, 4242420003))),
+			asn4:        true,
+			segments:    []ASPathSegment{{Type: SegmentASSequence, ASNs: []uint32{4242420001, 4242420002, 4242420003}}},
+			origin:      OriginEGP,
+			adjacencies: [][2]uint32{{4242420001, 4242420002}, {4242420002, 4242420003}},
+		},
+		{
+			name: "2-byte AS_PATH merged with AS4_PATH",
+			data: concat(
+				attribute(2, segment(SegmentASSequence, 2, 64512, 23456, 23456)),
+				attribute(17, segment(SegmentASSequence, 4, 4242420002, 4242420003)),
+			),
+			segments: []ASPathSegment{
+				{Type: SegmentASSequence, ASNs: []uint32{64512}},
+				{Type: SegmentASSequence, ASNs: []uint32{4242420002, 4242420003}},
+			},
+			adjacencies: [][2]uint32{{64512, 4242420002}, {4242420002, 4242420003}},
+		},
+		{
+			name: "AS_SET",
+			data: attribute(2, concat(
+				segment(SegmentASSequence, 4, 4242420001, 4242420002),
+				segment(SegmentASSet, 4, 4242420003, 4242420004),
+			)),
+			asn4: true,
+			segments: []ASPathSegment{
+				{Type: SegmentASSequence, ASNs: []uint32{4242420001, 4242420002}},
+				{Type: SegmentASSet, ASNs: []uint32{4242420003, 4242420004}},
+			},
+			flags:       PathFlagASSet,
+			adjacencies: [][2]uint32{{4242420001, 4242420002}},
+		},
+		{
+			name: "confederation",
+			data: attribute(2, concat(
+				segment(SegmentASConfedSequence, 4, 64512, 64513),
+				segment(SegmentASSequence, 4, 4242420001, 4242420002),
+			)),
+			asn4: true,
+			segments: []ASPathSegment{
+				{Type: SegmentASConfedSequence, ASNs: []uint32{64512, 64513}},
+				{Type: SegmentASSequence, ASNs: []uint32{4242420001, 4242420002}},
+			},
+			flags:       PathFlagConfed,
+			adjacencies: [][2]uint32{{4242420001, 4242420002}},
+		},
+		{
+			name: "communities",
+			data: concat(
+				attribute(2, segment(SegmentASSequence, 4, 4242420001)),
+				attribute(8, []byte{0xfb, 0xff, 0, 3, 0xfb, 0xff, 0, 24}),
+				attribute(32, []byte{0xfc, 0xde, 0x32, 0x01, 0, 0, 0, 1, 0, 0, 0, 2}),
+			),
+			asn4:             true,
+			segments:         []ASPathSegment{{Type: SegmentASSequence, ASNs: []uint32{4242420001}}},
+			communities:      []uint32{64511<<16 | 3, 64511<<16 | 24},
+			largeCommunities: []LargeCommunity{{GlobalAdmin: 4242420225, LocalData1: 1, LocalData2: 2}},
+		},
+		{
+			name:     "extended length",
+			data:     attribute(2, segment(SegmentASSequence, 4, longSet...)),
+			asn4:     true,
+			segments: []ASPathSegment{{Type: SegmentASSequence, ASNs: longSet}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs, err := decodeAttributes(tt.data, tt.asn4)
+			if err != nil {
+				t.Fatalf("decodeAttributes() error = %v", err)
+			}
+			asPath := attrs.asPath
+			if !reflect.DeepEqual(asPath.Segments, tt.segments) {
+				t.Errorf("segments = %v, want %v", asPath.Segments, tt.segments)
+			}
+			if asPath.Flags != tt.flags {
+				t.Errorf("flags = %d, want %d", asPath.Flags, tt.flags)
+			}
+			if asPath.OriginType != tt.origin {
+				t.Errorf("origin = %d, want %d", asPath.OriginType, tt.origin)
+			}
+			if !reflect.DeepEqual(asPath.Communities, tt.communities) {
+				t.Errorf("communities = %v, want %v", asPath.Communities, tt.communities)
+			}
+			if !reflect.DeepEqual(asPath.LargeCommunities, tt.largeCommunities) {
+				t.Errorf("large communities = %v, want %v", asPath.LargeCommunities, tt.largeCommunities)
+			}
+			if tt.adjacencies != nil && !reflect.DeepEqual(asPath.Adjacencies(), tt.adjacencies) {
+				t.Errorf("adjacencies = %v, want %v", asPath.Adjacencies(), tt.adjacencies)
+			}
+		})
+	}
+}
+
+func TestASPathOrigin(t *testing.T) {
+	tests := []struct {
+		name     string
+		segments []ASPathSegment
+		want     uint32
+	}{
+		{"sequence", []ASPathSegment{{Type: SegmentASSequence, ASNs: []uint32{1, 2, 3}}}, 3},
+		{"aggregated", []ASPathSegment{
+			{Type: SegmentASSequence, ASNs: []uint32{1, 2}},
+			{Type: SegmentASSet, ASNs: []uint32{3, 4}},
+		}, 2},
+		{"set only", []ASPathSegment{{Type: SegmentASSet, ASNs: []uint32{3, 4}}}, 4},
+		{"empty", nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asPath := newASPath(tt.segments)
+			if got := asPath.Origin(); got != tt.want {
+				t.Errorf("Origin() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
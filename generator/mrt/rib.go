@@ -0,0 +1,184 @@
+package mrt
+
+import (
+	"sort"
+	"sync"
+)
+
+// RIBKey identifies a path in the RIB
+type RIBKey struct {
+	PeerIP  string
+	PeerASN uint32
+	Prefix  Route
+	PathID  uint32
+	AF      uint32
+}
+
+// RIB is a routing table built from a TABLE_DUMP_V2 snapshot, on top of
+// which BGP4MP updates can be applied incrementally
+type RIB struct {
+	sync.Mutex
+	entries       map[RIBKey]ASPath
+	peers         map[RIBKey]*Peer // Known peers, keyed by PeerIP and PeerASN only
+	peerTables    []*PeerIndexTable
+	withdrawals   map[[2]uint32]uint32
+	baseTimestamp uint64 // Time of the base snapshot, older updates are ignored
+	timestamp     uint64 // Time of the latest applied update
+}
+
+// NewRIB creates a RIB from the merged result of a full MRT dump
+func NewRIB(base *Result) *RIB {
+	rib := &RIB{
+		entries:     make(map[RIBKey]ASPath, len(base.ASPaths)),
+		peers:       make(map[RIBKey]*Peer),
+		peerTables:  base.PeerTables,
+		withdrawals: make(map[[2]uint32]uint32),
+	}
+	if base.Metadata != nil {
+		rib.baseTimestamp = base.Metadata.Timestamp
+		rib.timestamp = base.Metadata.Timestamp
+	}
+
+	for _, table := range base.PeerTables {
+		for _, peer := range table.Peers {
+			if _, exists := rib.peers[peerKey(peer)]; !exists {
+				rib.peers[peerKey(peer)] = peer
+			}
+		}
+	}
+
+	for _, asp := range base.ASPaths {
+		rib.entries[ribKey(&asp)] = asp
+	}
+
+	return rib
+}
+
+// peerKey returns the key identifying a peer in the RIB
+func peerKey(peer *Peer) RIBKey {
+	if peer == nil {
+		return RIBKey{}
+	}
+	return RIBKey{PeerIP: peer.IP, PeerASN: peer.ASN}
+}
+
+// ribKey returns the key identifying a path in the RIB
+func ribKey(asp *ASPath) RIBKey {
+	key := peerKey(asp.Peer)
+	key.Prefix = asp.Prefix
+	key.PathID = asp.PathID
+	key.AF = asp.AF
+	return key
+}
+
+// Apply applies announcements and withdrawals in time order and returns the
+// number of updates that changed the RIB. Updates older than the base
+// snapshot or the path they would replace are ignored.
+func (r *RIB) Apply(updates []Update) int {
+	r.Lock()
+	defer r.Unlock()
+
+	sorted := make([]Update, len(updates))
+	copy(sorted, updates)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Path.Timestamp < sorted[j].Path.Timestamp
+	})
+
+	applied := 0
+	for _, update := range sorted {
+		path := update.Path
+		if uint64(path.Timestamp) < r.baseTimestamp {
+			continue
+		}
+
+		// Resolve the session to the peer entry of the base snapshot if known
+		if path.Peer != nil {
+			if peer, exists := r.peers[peerKey(path.Peer)]; exists {
+				path.Peer = peer
+			} else {
+				r.addPeer(path.Peer)
+			}
+		}
+
+		key := ribKey(&path)
+		existing, exists := r.entries[key]
+		if exists && path.Timestamp < existing.Timestamp {
+			continue
+		}
+
+		if update.Withdraw {
+			if !exists {
+				continue
+			}
+			r.recordWithdrawals(&existing, nil, path.Timestamp)
+			delete(r.entries, key)
+		} else {
+			if exists {
+				r.recordWithdrawals(&existing, &path, path.Timestamp)
+			}
+			r.entries[key] = path
+		}
+
+		applied++
+		r.timestamp = max(r.timestamp, uint64(path.Timestamp))
+	}
+
+	return applied
+}
+
+// addPeer registers a peer only seen in update streams
+func (r *RIB) addPeer(peer *Peer) {
+	r.peers[peerKey(peer)] = peer
+
+	var table *PeerIndexTable
+	if len(r.peerTables) > 0 && r.peerTables[len(r.peerTables)-1].CollectorBGPID == "" {
+		table = r.peerTables[len(r.peerTables)-1]
+	} else {
+		table = &PeerIndexTable{}
+		r.peerTables = append(r.peerTables, table)
+	}
+	table.Peers = append(table.Peers, peer)
+}
+
+// recordWithdrawals records the adjacencies of old that are no longer
+// present in replacement as withdrawn at the given time
+func (r *RIB) recordWithdrawals(old, replacement *ASPath, timestamp uint32) {
+	kept := make(map[[2]uint32]struct{})
+	if replacement != nil {
+		for _, adj := range replacement.Adjacencies() {
+			kept[adj] = struct{}{}
+		}
+	}
+
+	for _, adj := range old.Adjacencies() {
+		if _, ok := kept[adj]; !ok {
+			r.withdrawals[adj] = max(r.withdrawals[adj], timestamp)
+		}
+	}
+}
+
+// Result returns a snapshot of the current RIB in the form of a processing result
+func (r *RIB) Result() *Result {
+	r.Lock()
+	defer r.Unlock()
+
+	result := &Result{
+		ASPaths:             make([]ASPath, 0, len(r.entries)),
+		Advertises:          make(map[uint32][]Route),
+		AdvertisesMulticast: make(map[uint32][]Route),
+		PeerTables:          r.peerTables,
+		Withdrawals:         make(map[[2]uint32]uint32, len(r.withdrawals)),
+		Metadata:            &Metadata{Timestamp: r.timestamp},
+	}
+
+	for _, asp := range r.entries {
+		result.ASPaths = append(result.ASPaths, asp)
+		addAdvertise(result, &asp)
+	}
+
+	for adj, timestamp := range r.withdrawals {
+		result.Withdrawals[adj] = timestamp
+	}
+
+	return result
+}
@@ -0,0 +1,115 @@
+package mrt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRIBApply(t *testing.T) {
+	peer := &Peer{IP: "172.20.0.1", ASN: 4242420001}
+	prefix := Route{Length: 24, IPType: "ipv4", IPValue: uint32(0xac140100)}
+
+	// path returns a path for prefix, updates carry their own copy of the
+	// peer as they do when decoded from BGP4MP
+	path := func(timestamp, pathID uint32, asns ...uint32) ASPath {
+		asPath := newASPath([]ASPathSegment{{Type: SegmentASSequence, ASNs: asns}})
+		asPath.Prefix = prefix
+		asPath.PathID = pathID
+		asPath.Timestamp = timestamp
+		asPath.AF = 1
+		asPath.Peer = &Peer{IP: peer.IP, ASN: peer.ASN}
+		return asPath
+	}
+	withdraw := func(timestamp, pathID uint32) Update {
+		return Update{Withdraw: true, Path: path(timestamp, pathID)}
+	}
+
+	tests := []struct {
+		name        string
+		updates     []Update
+		applied     int
+		paths       [][]uint32
+		withdrawals map[[2]uint32]uint32
+	}{
+		{
+			name:        "withdrawal",
+			updates:     []Update{withdraw(200, 0)},
+			applied:     1,
+			withdrawals: map[[2]uint32]uint32{{1, 2}: 200, {2, 3}: 200},
+		},
+		{
+			name:        "replacement",
+			updates:     []Update{{Path: path(200, 0, 1, 4, 3)}},
+			applied:     1,
+			paths:       [][]uint32{{1, 4, 3}},
+			withdrawals: map[[2]uint32]uint32{{1, 2}: 200, {2, 3}: 200},
+		},
+		{
+			name:        "partial replacement",
+			updates:     []Update{{Path: path(200, 0, 1, 2, 4)}},
+			applied:     1,
+			paths:       [][]uint32{{1, 2, 4}},
+			withdrawals: map[[2]uint32]uint32{{2, 3}: 200},
+		},
+		{
+			name:        "additional path",
+			updates:     []Update{{Path: path(200, 1, 1, 4, 3)}},
+			applied:     1,
+			paths:       [][]uint32{{1, 2, 3}, {1, 4, 3}},
+			withdrawals: map[[2]uint32]uint32{},
+		},
+		{
+			name:        "older than the base snapshot",
+			updates:     []Update{withdraw(50, 0)},
+			paths:       [][]uint32{{1, 2, 3}},
+			withdrawals: map[[2]uint32]uint32{},
+		},
+		{
+			name:        "withdrawal of an unknown path",
+			updates:     []Update{withdraw(200, 7)},
+			paths:       [][]uint32{{1, 2, 3}},
+			withdrawals: map[[2]uint32]uint32{},
+		},
+		{
+			name:        "applied in time order",
+			updates:     []Update{{Path: path(300, 0, 1, 4, 3)}, withdraw(200, 0)},
+			applied:     2,
+			paths:       [][]uint32{{1, 4, 3}},
+			withdrawals: map[[2]uint32]uint32{{1, 2}: 200, {2, 3}: 200},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			basePath := path(100, 0, 1, 2, 3)
+			basePath.Peer = peer
+			base := &Result{
+				ASPaths:    []ASPath{basePath},
+				PeerTables: []*PeerIndexTable{{Peers: []*Peer{peer}}},
+				Metadata:   &Metadata{Timestamp: 100},
+			}
+			rib := NewRIB(base)
+			if applied := rib.Apply(tt.updates); applied != tt.applied {
+				t.Errorf("Apply() = %d, want %d", applied, tt.applied)
+			}
+
+			result := rib.Result()
+			var paths [][]uint32
+			for _, asp := range result.ASPaths {
+				if asp.Peer != peer {
+					t.Errorf("path %v is not attributed to the peer of the base snapshot", asp.Path)
+				}
+				paths = append(paths, asp.Path)
+			}
+			if len(paths) > 1 && paths[0][1] > paths[1][1] {
+				paths[0], paths[1] = paths[1], paths[0]
+			}
+			if !reflect.DeepEqual(paths, tt.paths) {
+				t.Errorf("paths = %v, want %v", paths, tt.paths)
+			}
+			if !reflect.DeepEqual(result.Withdrawals, tt.withdrawals) {
+				t.Errorf("withdrawals = %v, want %v", result.Withdrawals, tt.withdrawals)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	pb "github.com/iedon/dn42_map_go/proto"
+	"github.com/iedon/dn42_map_go/roa"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// writeFileAtomic writes data to a temporary file next to path, syncs it to
+// disk and renames it over path, so readers never see a partial file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	// Clean up the temporary file on any failure
+	ok := false
+	defer func() {
+		if !ok {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	ok = true
+
+	// Persist the rename, not supported on every platform
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// rotateOutputs keeps up to keep previous versions of path as path.1 (newest)
+// to path.<keep> (oldest). The current file stays in place until it is
+// replaced by the rename in writeFileAtomic.
+func rotateOutputs(path string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	os.Remove(fmt.Sprintf("%s.%d", path, keep))
+	for i := keep - 1; i >= 1; i-- {
+		older := fmt.Sprintf("%s.%d", path, i)
+		if _, err := os.Stat(older); err == nil {
+			if err := os.Rename(older, fmt.Sprintf("%s.%d", path, i+1)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return copyFile(path, path+".1")
+}
+
+// copyFile copies src to dst, using a hard link where possible
+func copyFile(src, dst string) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// loadGraph reads a previously written map file
+func loadGraph(path string) (*pb.Graph, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	graph := &pb.Graph{}
+	if err := proto.Unmarshal(data, graph); err != nil {
+		return nil, err
+	}
+	return graph, nil
+}
+
+// checkGraphSanity refuses to replace a healthy map with a degenerate one,
+// i.e. one with fewer nodes or links than the given fraction of the previous map
+func checkGraphSanity(prev, next *pb.Graph, minNodeRatio, minLinkRatio float64) error {
+	if prev == nil {
+		return nil
+	}
+
+	if minNodeRatio > 0 && float64(len(next.Nodes)) < float64(len(prev.Nodes))*minNodeRatio {
+		return fmt.Errorf("new map has %d nodes, less than %.0f%% of the previous %d",
+			len(next.Nodes), minNodeRatio*100, len(prev.Nodes))
+	}
+	if minLinkRatio > 0 && float64(len(next.Links)) < float64(len(prev.Links))*minLinkRatio {
+		return fmt.Errorf("new map has %d links, less than %.0f%% of the previous %d",
+			len(next.Links), minLinkRatio*100, len(prev.Links))
+	}
+	return nil
+}
+
+// writeROAOutputs writes the configured ROA tables. Failures are logged and
+// do not fail the generation.
+func writeROAOutputs(config ROAExport, roas *roa.Set) {
+	outputs := []struct {
+		path  string
+		write func(w io.Writer) error
+	}{
+		{config.BirdROA4File, func(w io.Writer) error { return roas.WriteBird(w, false) }},
+		{config.BirdROA6File, func(w io.Writer) error { return roas.WriteBird(w, true) }},
+		{config.JSONFile, roas.WriteJSON},
+	}
+	for _, output := range outputs {
+		if output.path == "" {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := output.write(&buf); err != nil {
+			log.Printf("failed to format ROA output %s: %v\n", output.path, err)
+			continue
+		}
+		if err := writeFileAtomic(output.path, buf.Bytes(), 0644); err != nil {
+			log.Printf("failed to write ROA output %s: %v\n", output.path, err)
+		}
+	}
+}
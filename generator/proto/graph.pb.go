@@ -21,16 +21,84 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Relationship is the inferred business relationship between the ASes of a link
+type Relationship int32
+
+const (
+	Relationship_RELATIONSHIP_UNKNOWN              Relationship = 0
+	Relationship_RELATIONSHIP_PROVIDER_TO_CUSTOMER Relationship = 1 // Source provides transit to target
+	Relationship_RELATIONSHIP_CUSTOMER_TO_PROVIDER Relationship = 2 // Target provides transit to source
+	Relationship_RELATIONSHIP_PEER                 Relationship = 3
+)
+
+// Enum value maps for Relationship.
+var (
+	Relationship_name = map[int32]string{
+		0: "RELATIONSHIP_UNKNOWN",
+		1: "RELATIONSHIP_PROVIDER_TO_CUSTOMER",
+		2: "RELATIONSHIP_CUSTOMER_TO_PROVIDER",
+		3: "RELATIONSHIP_PEER",
+	}
+	Relationship_value = map[string]int32{
+		"RELATIONSHIP_UNKNOWN":              0,
+		"RELATIONSHIP_PROVIDER_TO_CUSTOMER": 1,
+		"RELATIONSHIP_CUSTOMER_TO_PROVIDER": 2,
+		"RELATIONSHIP_PEER":                 3,
+	}
+)
+
+func (x Relationship) Enum() *Relationship {
+	p := new(Relationship)
+	*p = x
+	return p
+}
+
+func (x Relationship) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Relationship) Descriptor() protoreflect.EnumDescriptor {
+	return file_graph_proto_enumTypes[0].Descriptor()
+}
+
+func (Relationship) Type() protoreflect.EnumType {
+	return &file_graph_proto_enumTypes[0]
+}
+
+func (x Relationship) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Relationship.Descriptor instead.
+func (Relationship) EnumDescriptor() ([]byte, []int) {
+	return file_graph_proto_rawDescGZIP(), []int{0}
+}
+
 // Node represents an AS node
 type Node struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	Asn             uint32                 `protobuf:"varint,1,opt,name=asn,proto3" json:"asn,omitempty"`
-	Desc            string                 `protobuf:"bytes,2,opt,name=desc,proto3" json:"desc,omitempty"`
-	Routes          []*Route               `protobuf:"bytes,3,rep,name=routes,proto3" json:"routes,omitempty"`
-	Centrality      *Centrality            `protobuf:"bytes,4,opt,name=centrality,proto3" json:"centrality,omitempty"`
-	RoutesMulticast []*Route               `protobuf:"bytes,5,rep,name=routes_multicast,json=routesMulticast,proto3" json:"routes_multicast,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	Asn                 uint32                 `protobuf:"varint,1,opt,name=asn,proto3" json:"asn,omitempty"`
+	Desc                string                 `protobuf:"bytes,2,opt,name=desc,proto3" json:"desc,omitempty"`
+	Routes              []*Route               `protobuf:"bytes,3,rep,name=routes,proto3" json:"routes,omitempty"`
+	Centrality          *Centrality            `protobuf:"bytes,4,opt,name=centrality,proto3" json:"centrality,omitempty"`
+	RoutesMulticast     []*Route               `protobuf:"bytes,5,rep,name=routes_multicast,json=routesMulticast,proto3" json:"routes_multicast,omitempty"`
+	PathFlags           uint32                 `protobuf:"varint,6,opt,name=path_flags,json=pathFlags,proto3" json:"path_flags,omitempty"`       // Bitmask: 1=seen in an AS_SET, 2=seen in a confederation segment
+	OriginTypes         uint32                 `protobuf:"varint,7,opt,name=origin_types,json=originTypes,proto3" json:"origin_types,omitempty"` // Bitmask of ORIGIN values on own prefixes: 1=IGP, 2=EGP, 4=INCOMPLETE
+	Regions             []uint32               `protobuf:"varint,8,rep,packed,name=regions,proto3" json:"regions,omitempty"`                     // DN42 region communities (64511:41-70) on own prefixes
+	Countries           []uint32               `protobuf:"varint,9,rep,packed,name=countries,proto3" json:"countries,omitempty"`                 // ISO 3166-1 numeric codes from DN42 country communities (64511:1000-1999)
+	Communities         []uint32               `protobuf:"varint,10,rep,packed,name=communities,proto3" json:"communities,omitempty"`            // All communities on own prefixes, high 16 bits ASN, low 16 bits value
+	LargeCommunities    []*LargeCommunity      `protobuf:"bytes,11,rep,name=large_communities,json=largeCommunities,proto3" json:"large_communities,omitempty"`
+	CustomerCone        *CustomerCone          `protobuf:"bytes,12,opt,name=customer_cone,json=customerCone,proto3" json:"customer_cone,omitempty"`
+	CentralityIpv4      *Centrality            `protobuf:"bytes,13,opt,name=centrality_ipv4,json=centralityIpv4,proto3" json:"centrality_ipv4,omitempty"`                // Centrality on the IPv4 unicast links, unset if the AS has none
+	CentralityIpv6      *Centrality            `protobuf:"bytes,14,opt,name=centrality_ipv6,json=centralityIpv6,proto3" json:"centrality_ipv6,omitempty"`                // Centrality on the IPv6 unicast links, unset if the AS has none
+	CentralityMulticast *Centrality            `protobuf:"bytes,15,opt,name=centrality_multicast,json=centralityMulticast,proto3" json:"centrality_multicast,omitempty"` // Centrality on the multicast links, unset if the AS has none
+	ArticulationPoint   bool                   `protobuf:"varint,16,opt,name=articulation_point,json=articulationPoint,proto3" json:"articulation_point,omitempty"`      // Removing the AS disconnects the graph
+	ArticulationCutOff  uint32                 `protobuf:"varint,17,opt,name=articulation_cut_off,json=articulationCutOff,proto3" json:"articulation_cut_off,omitempty"` // ASes cut off from the largest remaining component without this AS
+	CoreNumber          uint32                 `protobuf:"varint,18,opt,name=core_number,json=coreNumber,proto3" json:"core_number,omitempty"`                           // Largest k of a k-core containing the AS
+	Community           uint32                 `protobuf:"varint,19,opt,name=community,proto3" json:"community,omitempty"`                                               // Louvain community, numbered by size with 0 the largest
+	RouteValidation     *RouteValidation       `protobuf:"bytes,20,opt,name=route_validation,json=routeValidation,proto3" json:"route_validation,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
 }
 
 func (x *Node) Reset() {
@@ -98,21 +166,326 @@ func (x *Node) GetRoutesMulticast() []*Route {
 	return nil
 }
 
-// Centrality stores the centrality metrics of a node
-type Centrality struct {
+func (x *Node) GetPathFlags() uint32 {
+	if x != nil {
+		return x.PathFlags
+	}
+	return 0
+}
+
+func (x *Node) GetOriginTypes() uint32 {
+	if x != nil {
+		return x.OriginTypes
+	}
+	return 0
+}
+
+func (x *Node) GetRegions() []uint32 {
+	if x != nil {
+		return x.Regions
+	}
+	return nil
+}
+
+func (x *Node) GetCountries() []uint32 {
+	if x != nil {
+		return x.Countries
+	}
+	return nil
+}
+
+func (x *Node) GetCommunities() []uint32 {
+	if x != nil {
+		return x.Communities
+	}
+	return nil
+}
+
+func (x *Node) GetLargeCommunities() []*LargeCommunity {
+	if x != nil {
+		return x.LargeCommunities
+	}
+	return nil
+}
+
+func (x *Node) GetCustomerCone() *CustomerCone {
+	if x != nil {
+		return x.CustomerCone
+	}
+	return nil
+}
+
+func (x *Node) GetCentralityIpv4() *Centrality {
+	if x != nil {
+		return x.CentralityIpv4
+	}
+	return nil
+}
+
+func (x *Node) GetCentralityIpv6() *Centrality {
+	if x != nil {
+		return x.CentralityIpv6
+	}
+	return nil
+}
+
+func (x *Node) GetCentralityMulticast() *Centrality {
+	if x != nil {
+		return x.CentralityMulticast
+	}
+	return nil
+}
+
+func (x *Node) GetArticulationPoint() bool {
+	if x != nil {
+		return x.ArticulationPoint
+	}
+	return false
+}
+
+func (x *Node) GetArticulationCutOff() uint32 {
+	if x != nil {
+		return x.ArticulationCutOff
+	}
+	return 0
+}
+
+func (x *Node) GetCoreNumber() uint32 {
+	if x != nil {
+		return x.CoreNumber
+	}
+	return 0
+}
+
+func (x *Node) GetCommunity() uint32 {
+	if x != nil {
+		return x.Community
+	}
+	return 0
+}
+
+func (x *Node) GetRouteValidation() *RouteValidation {
+	if x != nil {
+		return x.RouteValidation
+	}
+	return nil
+}
+
+// CustomerCone is the set of ASes reachable over inferred provider->customer
+// links, including the AS itself
+type CustomerCone struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Asns              uint32                 `protobuf:"varint,1,opt,name=asns,proto3" json:"asns,omitempty"`
+	Prefixes          uint32                 `protobuf:"varint,2,opt,name=prefixes,proto3" json:"prefixes,omitempty"`                                            // Distinct unicast prefixes originated within the cone
+	PrefixesMulticast uint32                 `protobuf:"varint,3,opt,name=prefixes_multicast,json=prefixesMulticast,proto3" json:"prefixes_multicast,omitempty"` // Distinct multicast prefixes originated within the cone
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *CustomerCone) Reset() {
+	*x = CustomerCone{}
+	mi := &file_graph_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CustomerCone) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CustomerCone) ProtoMessage() {}
+
+func (x *CustomerCone) ProtoReflect() protoreflect.Message {
+	mi := &file_graph_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CustomerCone.ProtoReflect.Descriptor instead.
+func (*CustomerCone) Descriptor() ([]byte, []int) {
+	return file_graph_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CustomerCone) GetAsns() uint32 {
+	if x != nil {
+		return x.Asns
+	}
+	return 0
+}
+
+func (x *CustomerCone) GetPrefixes() uint32 {
+	if x != nil {
+		return x.Prefixes
+	}
+	return 0
+}
+
+func (x *CustomerCone) GetPrefixesMulticast() uint32 {
+	if x != nil {
+		return x.PrefixesMulticast
+	}
+	return 0
+}
+
+// RouteValidation counts the unicast routes of an AS by their origin
+// validation state against the registry's route and route6 objects
+type RouteValidation struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Degree        float64                `protobuf:"fixed64,1,opt,name=degree,proto3" json:"degree,omitempty"`
-	Betweenness   float64                `protobuf:"fixed64,2,opt,name=betweenness,proto3" json:"betweenness,omitempty"`
-	Closeness     float64                `protobuf:"fixed64,3,opt,name=closeness,proto3" json:"closeness,omitempty"`
-	Index         uint32                 `protobuf:"varint,4,opt,name=index,proto3" json:"index,omitempty"` // dn42Index
-	Ranking       uint32                 `protobuf:"varint,5,opt,name=ranking,proto3" json:"ranking,omitempty"`
+	Valid         uint32                 `protobuf:"varint,1,opt,name=valid,proto3" json:"valid,omitempty"`
+	InvalidOrigin uint32                 `protobuf:"varint,2,opt,name=invalid_origin,json=invalidOrigin,proto3" json:"invalid_origin,omitempty"` // Covered only by ROAs for other origins
+	InvalidLength uint32                 `protobuf:"varint,3,opt,name=invalid_length,json=invalidLength,proto3" json:"invalid_length,omitempty"` // Longer than the max-length of the ROAs for the origin
+	NotFound      uint32                 `protobuf:"varint,4,opt,name=not_found,json=notFound,proto3" json:"not_found,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
+func (x *RouteValidation) Reset() {
+	*x = RouteValidation{}
+	mi := &file_graph_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RouteValidation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RouteValidation) ProtoMessage() {}
+
+func (x *RouteValidation) ProtoReflect() protoreflect.Message {
+	mi := &file_graph_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RouteValidation.ProtoReflect.Descriptor instead.
+func (*RouteValidation) Descriptor() ([]byte, []int) {
+	return file_graph_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RouteValidation) GetValid() uint32 {
+	if x != nil {
+		return x.Valid
+	}
+	return 0
+}
+
+func (x *RouteValidation) GetInvalidOrigin() uint32 {
+	if x != nil {
+		return x.InvalidOrigin
+	}
+	return 0
+}
+
+func (x *RouteValidation) GetInvalidLength() uint32 {
+	if x != nil {
+		return x.InvalidLength
+	}
+	return 0
+}
+
+func (x *RouteValidation) GetNotFound() uint32 {
+	if x != nil {
+		return x.NotFound
+	}
+	return 0
+}
+
+// LargeCommunity represents a BGP large community
+type LargeCommunity struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GlobalAdmin   uint32                 `protobuf:"varint,1,opt,name=global_admin,json=globalAdmin,proto3" json:"global_admin,omitempty"`
+	LocalData1    uint32                 `protobuf:"varint,2,opt,name=local_data1,json=localData1,proto3" json:"local_data1,omitempty"`
+	LocalData2    uint32                 `protobuf:"varint,3,opt,name=local_data2,json=localData2,proto3" json:"local_data2,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LargeCommunity) Reset() {
+	*x = LargeCommunity{}
+	mi := &file_graph_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LargeCommunity) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LargeCommunity) ProtoMessage() {}
+
+func (x *LargeCommunity) ProtoReflect() protoreflect.Message {
+	mi := &file_graph_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LargeCommunity.ProtoReflect.Descriptor instead.
+func (*LargeCommunity) Descriptor() ([]byte, []int) {
+	return file_graph_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *LargeCommunity) GetGlobalAdmin() uint32 {
+	if x != nil {
+		return x.GlobalAdmin
+	}
+	return 0
+}
+
+func (x *LargeCommunity) GetLocalData1() uint32 {
+	if x != nil {
+		return x.LocalData1
+	}
+	return 0
+}
+
+func (x *LargeCommunity) GetLocalData2() uint32 {
+	if x != nil {
+		return x.LocalData2
+	}
+	return 0
+}
+
+// Centrality stores the centrality metrics of a node
+type Centrality struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Degree         float64                `protobuf:"fixed64,1,opt,name=degree,proto3" json:"degree,omitempty"`
+	Betweenness    float64                `protobuf:"fixed64,2,opt,name=betweenness,proto3" json:"betweenness,omitempty"`
+	Closeness      float64                `protobuf:"fixed64,3,opt,name=closeness,proto3" json:"closeness,omitempty"`
+	Index          uint32                 `protobuf:"varint,4,opt,name=index,proto3" json:"index,omitempty"` // dn42Index
+	Ranking        uint32                 `protobuf:"varint,5,opt,name=ranking,proto3" json:"ranking,omitempty"`
+	Pagerank       float64                `protobuf:"fixed64,6,opt,name=pagerank,proto3" json:"pagerank,omitempty"`       // PageRank on the directed links
+	Eigenvector    float64                `protobuf:"fixed64,7,opt,name=eigenvector,proto3" json:"eigenvector,omitempty"` // Eigenvector centrality on the undirected graph
+	Harmonic       float64                `protobuf:"fixed64,8,opt,name=harmonic,proto3" json:"harmonic,omitempty"`       // Harmonic closeness, normalized by node count - 1
+	InDegree       float64                `protobuf:"fixed64,9,opt,name=in_degree,json=inDegree,proto3" json:"in_degree,omitempty"`
+	OutDegree      float64                `protobuf:"fixed64,10,opt,name=out_degree,json=outDegree,proto3" json:"out_degree,omitempty"`
+	InBetweenness  float64                `protobuf:"fixed64,11,opt,name=in_betweenness,json=inBetweenness,proto3" json:"in_betweenness,omitempty"`    // Directed shortest paths with the node as first hop after the source
+	OutBetweenness float64                `protobuf:"fixed64,12,opt,name=out_betweenness,json=outBetweenness,proto3" json:"out_betweenness,omitempty"` // Directed shortest paths with the node as last hop before the target
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
 func (x *Centrality) Reset() {
 	*x = Centrality{}
-	mi := &file_graph_proto_msgTypes[1]
+	mi := &file_graph_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -124,7 +497,7 @@ func (x *Centrality) String() string {
 func (*Centrality) ProtoMessage() {}
 
 func (x *Centrality) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_proto_msgTypes[1]
+	mi := &file_graph_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -137,7 +510,7 @@ func (x *Centrality) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Centrality.ProtoReflect.Descriptor instead.
 func (*Centrality) Descriptor() ([]byte, []int) {
-	return file_graph_proto_rawDescGZIP(), []int{1}
+	return file_graph_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *Centrality) GetDegree() float64 {
@@ -149,28 +522,77 @@ func (x *Centrality) GetDegree() float64 {
 
 func (x *Centrality) GetBetweenness() float64 {
 	if x != nil {
-		return x.Betweenness
+		return x.Betweenness
+	}
+	return 0
+}
+
+func (x *Centrality) GetCloseness() float64 {
+	if x != nil {
+		return x.Closeness
+	}
+	return 0
+}
+
+func (x *Centrality) GetIndex() uint32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *Centrality) GetRanking() uint32 {
+	if x != nil {
+		return x.Ranking
+	}
+	return 0
+}
+
+func (x *Centrality) GetPagerank() float64 {
+	if x != nil {
+		return x.Pagerank
+	}
+	return 0
+}
+
+func (x *Centrality) GetEigenvector() float64 {
+	if x != nil {
+		return x.Eigenvector
+	}
+	return 0
+}
+
+func (x *Centrality) GetHarmonic() float64 {
+	if x != nil {
+		return x.Harmonic
+	}
+	return 0
+}
+
+func (x *Centrality) GetInDegree() float64 {
+	if x != nil {
+		return x.InDegree
 	}
 	return 0
 }
 
-func (x *Centrality) GetCloseness() float64 {
+func (x *Centrality) GetOutDegree() float64 {
 	if x != nil {
-		return x.Closeness
+		return x.OutDegree
 	}
 	return 0
 }
 
-func (x *Centrality) GetIndex() uint32 {
+func (x *Centrality) GetInBetweenness() float64 {
 	if x != nil {
-		return x.Index
+		return x.InBetweenness
 	}
 	return 0
 }
 
-func (x *Centrality) GetRanking() uint32 {
+func (x *Centrality) GetOutBetweenness() float64 {
 	if x != nil {
-		return x.Ranking
+		return x.OutBetweenness
 	}
 	return 0
 }
@@ -190,7 +612,7 @@ type Route struct {
 
 func (x *Route) Reset() {
 	*x = Route{}
-	mi := &file_graph_proto_msgTypes[2]
+	mi := &file_graph_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -202,7 +624,7 @@ func (x *Route) String() string {
 func (*Route) ProtoMessage() {}
 
 func (x *Route) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_proto_msgTypes[2]
+	mi := &file_graph_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -215,7 +637,7 @@ func (x *Route) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Route.ProtoReflect.Descriptor instead.
 func (*Route) Descriptor() ([]byte, []int) {
-	return file_graph_proto_rawDescGZIP(), []int{2}
+	return file_graph_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *Route) GetLength() uint32 {
@@ -279,7 +701,7 @@ type IPv6 struct {
 
 func (x *IPv6) Reset() {
 	*x = IPv6{}
-	mi := &file_graph_proto_msgTypes[3]
+	mi := &file_graph_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -291,7 +713,7 @@ func (x *IPv6) String() string {
 func (*IPv6) ProtoMessage() {}
 
 func (x *IPv6) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_proto_msgTypes[3]
+	mi := &file_graph_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -304,7 +726,7 @@ func (x *IPv6) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use IPv6.ProtoReflect.Descriptor instead.
 func (*IPv6) Descriptor() ([]byte, []int) {
-	return file_graph_proto_rawDescGZIP(), []int{3}
+	return file_graph_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *IPv6) GetHighH32() uint32 {
@@ -337,17 +759,30 @@ func (x *IPv6) GetLowL32() uint32 {
 
 // Link represents a connection between two ASes
 type Link struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Source        uint32                 `protobuf:"varint,1,opt,name=source,proto3" json:"source,omitempty"`
-	Target        uint32                 `protobuf:"varint,2,opt,name=target,proto3" json:"target,omitempty"`
-	Af            uint32                 `protobuf:"varint,3,opt,name=af,proto3" json:"af,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	Source                 uint32                 `protobuf:"varint,1,opt,name=source,proto3" json:"source,omitempty"`
+	Target                 uint32                 `protobuf:"varint,2,opt,name=target,proto3" json:"target,omitempty"`
+	Af                     uint32                 `protobuf:"varint,3,opt,name=af,proto3" json:"af,omitempty"`
+	Peers                  []uint32               `protobuf:"varint,4,rep,packed,name=peers,proto3" json:"peers,omitempty"`                                                            // Indexes into Graph.peers of the feeders that saw this link
+	PathFlags              uint32                 `protobuf:"varint,5,opt,name=path_flags,json=pathFlags,proto3" json:"path_flags,omitempty"`                                          // Bitmask: 1=seen on a path with an AS_SET, 2=seen on a path with a confederation segment
+	LastAnnounced          uint64                 `protobuf:"varint,6,opt,name=last_announced,json=lastAnnounced,proto3" json:"last_announced,omitempty"`                              // Unix time a path over this link was last announced
+	LastWithdrawn          uint64                 `protobuf:"varint,7,opt,name=last_withdrawn,json=lastWithdrawn,proto3" json:"last_withdrawn,omitempty"`                              // Unix time a path over this link was last withdrawn, 0 if never
+	Latency                uint32                 `protobuf:"varint,8,opt,name=latency,proto3" json:"latency,omitempty"`                                                               // Best DN42 latency tier (1-9) seen on paths over this link, 0 if unknown
+	Bandwidth              uint32                 `protobuf:"varint,9,opt,name=bandwidth,proto3" json:"bandwidth,omitempty"`                                                           // Best DN42 bandwidth tier (1-9, 64511:21-29) seen on paths over this link, 0 if unknown
+	Crypto                 uint32                 `protobuf:"varint,10,opt,name=crypto,proto3" json:"crypto,omitempty"`                                                                // Best DN42 crypto level (1-4, 64511:31-34) seen on paths over this link, 0 if unknown
+	Relationship           Relationship           `protobuf:"varint,11,opt,name=relationship,proto3,enum=dn42_map.Relationship" json:"relationship,omitempty"`                         // Inferred relationship of source to target
+	RelationshipConfidence float64                `protobuf:"fixed64,12,opt,name=relationship_confidence,json=relationshipConfidence,proto3" json:"relationship_confidence,omitempty"` // Confidence of the inferred relationship, 0 to 1
+	PathCount              uint32                 `protobuf:"varint,13,opt,name=path_count,json=pathCount,proto3" json:"path_count,omitempty"`                                         // Distinct AS paths traversing this link
+	PrefixCount            uint32                 `protobuf:"varint,14,opt,name=prefix_count,json=prefixCount,proto3" json:"prefix_count,omitempty"`                                   // Distinct prefixes announced over this link
+	Bridge                 bool                   `protobuf:"varint,15,opt,name=bridge,proto3" json:"bridge,omitempty"`                                                                // Removing the link in both directions disconnects the graph
+	BridgeCutOff           uint32                 `protobuf:"varint,16,opt,name=bridge_cut_off,json=bridgeCutOff,proto3" json:"bridge_cut_off,omitempty"`                              // ASes in the smaller component left without this link
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
 }
 
 func (x *Link) Reset() {
 	*x = Link{}
-	mi := &file_graph_proto_msgTypes[4]
+	mi := &file_graph_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -359,7 +794,7 @@ func (x *Link) String() string {
 func (*Link) ProtoMessage() {}
 
 func (x *Link) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_proto_msgTypes[4]
+	mi := &file_graph_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -372,7 +807,7 @@ func (x *Link) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Link.ProtoReflect.Descriptor instead.
 func (*Link) Descriptor() ([]byte, []int) {
-	return file_graph_proto_rawDescGZIP(), []int{4}
+	return file_graph_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *Link) GetSource() uint32 {
@@ -396,6 +831,235 @@ func (x *Link) GetAf() uint32 {
 	return 0
 }
 
+func (x *Link) GetPeers() []uint32 {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+func (x *Link) GetPathFlags() uint32 {
+	if x != nil {
+		return x.PathFlags
+	}
+	return 0
+}
+
+func (x *Link) GetLastAnnounced() uint64 {
+	if x != nil {
+		return x.LastAnnounced
+	}
+	return 0
+}
+
+func (x *Link) GetLastWithdrawn() uint64 {
+	if x != nil {
+		return x.LastWithdrawn
+	}
+	return 0
+}
+
+func (x *Link) GetLatency() uint32 {
+	if x != nil {
+		return x.Latency
+	}
+	return 0
+}
+
+func (x *Link) GetBandwidth() uint32 {
+	if x != nil {
+		return x.Bandwidth
+	}
+	return 0
+}
+
+func (x *Link) GetCrypto() uint32 {
+	if x != nil {
+		return x.Crypto
+	}
+	return 0
+}
+
+func (x *Link) GetRelationship() Relationship {
+	if x != nil {
+		return x.Relationship
+	}
+	return Relationship_RELATIONSHIP_UNKNOWN
+}
+
+func (x *Link) GetRelationshipConfidence() float64 {
+	if x != nil {
+		return x.RelationshipConfidence
+	}
+	return 0
+}
+
+func (x *Link) GetPathCount() uint32 {
+	if x != nil {
+		return x.PathCount
+	}
+	return 0
+}
+
+func (x *Link) GetPrefixCount() uint32 {
+	if x != nil {
+		return x.PrefixCount
+	}
+	return 0
+}
+
+func (x *Link) GetBridge() bool {
+	if x != nil {
+		return x.Bridge
+	}
+	return false
+}
+
+func (x *Link) GetBridgeCutOff() uint32 {
+	if x != nil {
+		return x.BridgeCutOff
+	}
+	return 0
+}
+
+// WithdrawnLink is an AS adjacency no longer announced on any path
+type WithdrawnLink struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Source        uint32                 `protobuf:"varint,1,opt,name=source,proto3" json:"source,omitempty"`                                    // ASN, the ASes may no longer be nodes of the graph
+	Target        uint32                 `protobuf:"varint,2,opt,name=target,proto3" json:"target,omitempty"`                                    // ASN
+	LastWithdrawn uint64                 `protobuf:"varint,3,opt,name=last_withdrawn,json=lastWithdrawn,proto3" json:"last_withdrawn,omitempty"` // Unix time the last path over this adjacency was withdrawn
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WithdrawnLink) Reset() {
+	*x = WithdrawnLink{}
+	mi := &file_graph_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WithdrawnLink) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WithdrawnLink) ProtoMessage() {}
+
+func (x *WithdrawnLink) ProtoReflect() protoreflect.Message {
+	mi := &file_graph_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WithdrawnLink.ProtoReflect.Descriptor instead.
+func (*WithdrawnLink) Descriptor() ([]byte, []int) {
+	return file_graph_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *WithdrawnLink) GetSource() uint32 {
+	if x != nil {
+		return x.Source
+	}
+	return 0
+}
+
+func (x *WithdrawnLink) GetTarget() uint32 {
+	if x != nil {
+		return x.Target
+	}
+	return 0
+}
+
+func (x *WithdrawnLink) GetLastWithdrawn() uint64 {
+	if x != nil {
+		return x.LastWithdrawn
+	}
+	return 0
+}
+
+// Peer represents a feeder peer of the route collector
+type Peer struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Asn            uint32                 `protobuf:"varint,1,opt,name=asn,proto3" json:"asn,omitempty"`
+	Ip             string                 `protobuf:"bytes,2,opt,name=ip,proto3" json:"ip,omitempty"`
+	BgpId          string                 `protobuf:"bytes,3,opt,name=bgp_id,json=bgpId,proto3" json:"bgp_id,omitempty"`
+	CollectorBgpId string                 `protobuf:"bytes,4,opt,name=collector_bgp_id,json=collectorBgpId,proto3" json:"collector_bgp_id,omitempty"`
+	ViewName       string                 `protobuf:"bytes,5,opt,name=view_name,json=viewName,proto3" json:"view_name,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Peer) Reset() {
+	*x = Peer{}
+	mi := &file_graph_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Peer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Peer) ProtoMessage() {}
+
+func (x *Peer) ProtoReflect() protoreflect.Message {
+	mi := &file_graph_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Peer.ProtoReflect.Descriptor instead.
+func (*Peer) Descriptor() ([]byte, []int) {
+	return file_graph_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Peer) GetAsn() uint32 {
+	if x != nil {
+		return x.Asn
+	}
+	return 0
+}
+
+func (x *Peer) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *Peer) GetBgpId() string {
+	if x != nil {
+		return x.BgpId
+	}
+	return ""
+}
+
+func (x *Peer) GetCollectorBgpId() string {
+	if x != nil {
+		return x.CollectorBgpId
+	}
+	return ""
+}
+
+func (x *Peer) GetViewName() string {
+	if x != nil {
+		return x.ViewName
+	}
+	return ""
+}
+
 // Contains metadata information about the graph.
 type Metadata struct {
 	state              protoimpl.MessageState `protogen:"open.v1"`
@@ -403,13 +1067,14 @@ type Metadata struct {
 	GeneratedTimestamp uint64                 `protobuf:"varint,2,opt,name=generated_timestamp,json=generatedTimestamp,proto3" json:"generated_timestamp,omitempty"`
 	DataTimestamp      uint64                 `protobuf:"varint,3,opt,name=data_timestamp,json=dataTimestamp,proto3" json:"data_timestamp,omitempty"`
 	Version            uint32                 `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"`
+	Sources            []*DataSource          `protobuf:"bytes,5,rep,name=sources,proto3" json:"sources,omitempty"`
 	unknownFields      protoimpl.UnknownFields
 	sizeCache          protoimpl.SizeCache
 }
 
 func (x *Metadata) Reset() {
 	*x = Metadata{}
-	mi := &file_graph_proto_msgTypes[5]
+	mi := &file_graph_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -421,7 +1086,7 @@ func (x *Metadata) String() string {
 func (*Metadata) ProtoMessage() {}
 
 func (x *Metadata) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_proto_msgTypes[5]
+	mi := &file_graph_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -434,7 +1099,7 @@ func (x *Metadata) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Metadata.ProtoReflect.Descriptor instead.
 func (*Metadata) Descriptor() ([]byte, []int) {
-	return file_graph_proto_rawDescGZIP(), []int{5}
+	return file_graph_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *Metadata) GetVendor() string {
@@ -465,19 +1130,105 @@ func (x *Metadata) GetVersion() uint32 {
 	return 0
 }
 
-// Graph represents the entire network topology
-type Graph struct {
+func (x *Metadata) GetSources() []*DataSource {
+	if x != nil {
+		return x.Sources
+	}
+	return nil
+}
+
+// DataSource records whether an MRT source went into the graph
+type DataSource struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Metadata      *Metadata              `protobuf:"bytes,1,opt,name=metadata,proto3" json:"metadata,omitempty"`
-	Nodes         []*Node                `protobuf:"bytes,2,rep,name=nodes,proto3" json:"nodes,omitempty"`
-	Links         []*Link                `protobuf:"bytes,3,rep,name=links,proto3" json:"links,omitempty"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Required      bool                   `protobuf:"varint,2,opt,name=required,proto3" json:"required,omitempty"`
+	Included      bool                   `protobuf:"varint,3,opt,name=included,proto3" json:"included,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"` // Why the source is missing, empty if included
+	DataTimestamp uint64                 `protobuf:"varint,5,opt,name=data_timestamp,json=dataTimestamp,proto3" json:"data_timestamp,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
+func (x *DataSource) Reset() {
+	*x = DataSource{}
+	mi := &file_graph_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DataSource) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DataSource) ProtoMessage() {}
+
+func (x *DataSource) ProtoReflect() protoreflect.Message {
+	mi := &file_graph_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DataSource.ProtoReflect.Descriptor instead.
+func (*DataSource) Descriptor() ([]byte, []int) {
+	return file_graph_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *DataSource) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *DataSource) GetRequired() bool {
+	if x != nil {
+		return x.Required
+	}
+	return false
+}
+
+func (x *DataSource) GetIncluded() bool {
+	if x != nil {
+		return x.Included
+	}
+	return false
+}
+
+func (x *DataSource) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *DataSource) GetDataTimestamp() uint64 {
+	if x != nil {
+		return x.DataTimestamp
+	}
+	return 0
+}
+
+// Graph represents the entire network topology
+type Graph struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Metadata       *Metadata              `protobuf:"bytes,1,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	Nodes          []*Node                `protobuf:"bytes,2,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	Links          []*Link                `protobuf:"bytes,3,rep,name=links,proto3" json:"links,omitempty"`
+	Peers          []*Peer                `protobuf:"bytes,4,rep,name=peers,proto3" json:"peers,omitempty"`
+	WithdrawnLinks []*WithdrawnLink       `protobuf:"bytes,5,rep,name=withdrawn_links,json=withdrawnLinks,proto3" json:"withdrawn_links,omitempty"` // Adjacencies withdrawn since the last full dump, set by incremental updates
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
 func (x *Graph) Reset() {
 	*x = Graph{}
-	mi := &file_graph_proto_msgTypes[6]
+	mi := &file_graph_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -489,7 +1240,7 @@ func (x *Graph) String() string {
 func (*Graph) ProtoMessage() {}
 
 func (x *Graph) ProtoReflect() protoreflect.Message {
-	mi := &file_graph_proto_msgTypes[6]
+	mi := &file_graph_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -502,7 +1253,7 @@ func (x *Graph) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Graph.ProtoReflect.Descriptor instead.
 func (*Graph) Descriptor() ([]byte, []int) {
-	return file_graph_proto_rawDescGZIP(), []int{6}
+	return file_graph_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *Graph) GetMetadata() *Metadata {
@@ -526,11 +1277,25 @@ func (x *Graph) GetLinks() []*Link {
 	return nil
 }
 
+func (x *Graph) GetPeers() []*Peer {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+func (x *Graph) GetWithdrawnLinks() []*WithdrawnLink {
+	if x != nil {
+		return x.WithdrawnLinks
+	}
+	return nil
+}
+
 var File_graph_proto protoreflect.FileDescriptor
 
 const file_graph_proto_rawDesc = "" +
 	"\n" +
-	"\vgraph.proto\x12\bdn42_map\"\xc7\x01\n" +
+	"\vgraph.proto\x12\bdn42_map\"\x94\a\n" +
 	"\x04Node\x12\x10\n" +
 	"\x03asn\x18\x01 \x01(\rR\x03asn\x12\x12\n" +
 	"\x04desc\x18\x02 \x01(\tR\x04desc\x12'\n" +
@@ -538,14 +1303,56 @@ const file_graph_proto_rawDesc = "" +
 	"\n" +
 	"centrality\x18\x04 \x01(\v2\x14.dn42_map.CentralityR\n" +
 	"centrality\x12:\n" +
-	"\x10routes_multicast\x18\x05 \x03(\v2\x0f.dn42_map.RouteR\x0froutesMulticast\"\x94\x01\n" +
+	"\x10routes_multicast\x18\x05 \x03(\v2\x0f.dn42_map.RouteR\x0froutesMulticast\x12\x1d\n" +
+	"\n" +
+	"path_flags\x18\x06 \x01(\rR\tpathFlags\x12!\n" +
+	"\forigin_types\x18\a \x01(\rR\voriginTypes\x12\x18\n" +
+	"\aregions\x18\b \x03(\rR\aregions\x12\x1c\n" +
+	"\tcountries\x18\t \x03(\rR\tcountries\x12 \n" +
+	"\vcommunities\x18\n" +
+	" \x03(\rR\vcommunities\x12E\n" +
+	"\x11large_communities\x18\v \x03(\v2\x18.dn42_map.LargeCommunityR\x10largeCommunities\x12;\n" +
+	"\rcustomer_cone\x18\f \x01(\v2\x16.dn42_map.CustomerConeR\fcustomerCone\x12=\n" +
+	"\x0fcentrality_ipv4\x18\r \x01(\v2\x14.dn42_map.CentralityR\x0ecentralityIpv4\x12=\n" +
+	"\x0fcentrality_ipv6\x18\x0e \x01(\v2\x14.dn42_map.CentralityR\x0ecentralityIpv6\x12G\n" +
+	"\x14centrality_multicast\x18\x0f \x01(\v2\x14.dn42_map.CentralityR\x13centralityMulticast\x12-\n" +
+	"\x12articulation_point\x18\x10 \x01(\bR\x11articulationPoint\x120\n" +
+	"\x14articulation_cut_off\x18\x11 \x01(\rR\x12articulationCutOff\x12\x1f\n" +
+	"\vcore_number\x18\x12 \x01(\rR\n" +
+	"coreNumber\x12\x1c\n" +
+	"\tcommunity\x18\x13 \x01(\rR\tcommunity\x12D\n" +
+	"\x10route_validation\x18\x14 \x01(\v2\x19.dn42_map.RouteValidationR\x0frouteValidation\"m\n" +
+	"\fCustomerCone\x12\x12\n" +
+	"\x04asns\x18\x01 \x01(\rR\x04asns\x12\x1a\n" +
+	"\bprefixes\x18\x02 \x01(\rR\bprefixes\x12-\n" +
+	"\x12prefixes_multicast\x18\x03 \x01(\rR\x11prefixesMulticast\"\x92\x01\n" +
+	"\x0fRouteValidation\x12\x14\n" +
+	"\x05valid\x18\x01 \x01(\rR\x05valid\x12%\n" +
+	"\x0einvalid_origin\x18\x02 \x01(\rR\rinvalidOrigin\x12%\n" +
+	"\x0einvalid_length\x18\x03 \x01(\rR\rinvalidLength\x12\x1b\n" +
+	"\tnot_found\x18\x04 \x01(\rR\bnotFound\"u\n" +
+	"\x0eLargeCommunity\x12!\n" +
+	"\fglobal_admin\x18\x01 \x01(\rR\vglobalAdmin\x12\x1f\n" +
+	"\vlocal_data1\x18\x02 \x01(\rR\n" +
+	"localData1\x12\x1f\n" +
+	"\vlocal_data2\x18\x03 \x01(\rR\n" +
+	"localData2\"\xfa\x02\n" +
 	"\n" +
 	"Centrality\x12\x16\n" +
 	"\x06degree\x18\x01 \x01(\x01R\x06degree\x12 \n" +
 	"\vbetweenness\x18\x02 \x01(\x01R\vbetweenness\x12\x1c\n" +
 	"\tcloseness\x18\x03 \x01(\x01R\tcloseness\x12\x14\n" +
 	"\x05index\x18\x04 \x01(\rR\x05index\x12\x18\n" +
-	"\aranking\x18\x05 \x01(\rR\aranking\"a\n" +
+	"\aranking\x18\x05 \x01(\rR\aranking\x12\x1a\n" +
+	"\bpagerank\x18\x06 \x01(\x01R\bpagerank\x12 \n" +
+	"\veigenvector\x18\a \x01(\x01R\veigenvector\x12\x1a\n" +
+	"\bharmonic\x18\b \x01(\x01R\bharmonic\x12\x1b\n" +
+	"\tin_degree\x18\t \x01(\x01R\binDegree\x12\x1d\n" +
+	"\n" +
+	"out_degree\x18\n" +
+	" \x01(\x01R\toutDegree\x12%\n" +
+	"\x0ein_betweenness\x18\v \x01(\x01R\rinBetweenness\x12'\n" +
+	"\x0fout_betweenness\x18\f \x01(\x01R\x0eoutBetweenness\"a\n" +
 	"\x05Route\x12\x16\n" +
 	"\x06length\x18\x01 \x01(\rR\x06length\x12\x14\n" +
 	"\x04ipv4\x18\x02 \x01(\rH\x00R\x04ipv4\x12$\n" +
@@ -555,20 +1362,61 @@ const file_graph_proto_rawDesc = "" +
 	"\bhigh_h32\x18\x01 \x01(\rR\ahighH32\x12\x19\n" +
 	"\bhigh_l32\x18\x02 \x01(\rR\ahighL32\x12\x17\n" +
 	"\alow_h32\x18\x03 \x01(\rR\x06lowH32\x12\x17\n" +
-	"\alow_l32\x18\x04 \x01(\rR\x06lowL32\"F\n" +
+	"\alow_l32\x18\x04 \x01(\rR\x06lowL32\"\x8e\x04\n" +
 	"\x04Link\x12\x16\n" +
 	"\x06source\x18\x01 \x01(\rR\x06source\x12\x16\n" +
 	"\x06target\x18\x02 \x01(\rR\x06target\x12\x0e\n" +
-	"\x02af\x18\x03 \x01(\rR\x02af\"\x94\x01\n" +
+	"\x02af\x18\x03 \x01(\rR\x02af\x12\x14\n" +
+	"\x05peers\x18\x04 \x03(\rR\x05peers\x12\x1d\n" +
+	"\n" +
+	"path_flags\x18\x05 \x01(\rR\tpathFlags\x12%\n" +
+	"\x0elast_announced\x18\x06 \x01(\x04R\rlastAnnounced\x12%\n" +
+	"\x0elast_withdrawn\x18\a \x01(\x04R\rlastWithdrawn\x12\x18\n" +
+	"\alatency\x18\b \x01(\rR\alatency\x12\x1c\n" +
+	"\tbandwidth\x18\t \x01(\rR\tbandwidth\x12\x16\n" +
+	"\x06crypto\x18\n" +
+	" \x01(\rR\x06crypto\x12:\n" +
+	"\frelationship\x18\v \x01(\x0e2\x16.dn42_map.RelationshipR\frelationship\x127\n" +
+	"\x17relationship_confidence\x18\f \x01(\x01R\x16relationshipConfidence\x12\x1d\n" +
+	"\n" +
+	"path_count\x18\r \x01(\rR\tpathCount\x12!\n" +
+	"\fprefix_count\x18\x0e \x01(\rR\vprefixCount\x12\x16\n" +
+	"\x06bridge\x18\x0f \x01(\bR\x06bridge\x12$\n" +
+	"\x0ebridge_cut_off\x18\x10 \x01(\rR\fbridgeCutOff\"f\n" +
+	"\rWithdrawnLink\x12\x16\n" +
+	"\x06source\x18\x01 \x01(\rR\x06source\x12\x16\n" +
+	"\x06target\x18\x02 \x01(\rR\x06target\x12%\n" +
+	"\x0elast_withdrawn\x18\x03 \x01(\x04R\rlastWithdrawn\"\x86\x01\n" +
+	"\x04Peer\x12\x10\n" +
+	"\x03asn\x18\x01 \x01(\rR\x03asn\x12\x0e\n" +
+	"\x02ip\x18\x02 \x01(\tR\x02ip\x12\x15\n" +
+	"\x06bgp_id\x18\x03 \x01(\tR\x05bgpId\x12(\n" +
+	"\x10collector_bgp_id\x18\x04 \x01(\tR\x0ecollectorBgpId\x12\x1b\n" +
+	"\tview_name\x18\x05 \x01(\tR\bviewName\"\xc4\x01\n" +
 	"\bMetadata\x12\x16\n" +
 	"\x06vendor\x18\x01 \x01(\tR\x06vendor\x12/\n" +
 	"\x13generated_timestamp\x18\x02 \x01(\x04R\x12generatedTimestamp\x12%\n" +
 	"\x0edata_timestamp\x18\x03 \x01(\x04R\rdataTimestamp\x12\x18\n" +
-	"\aversion\x18\x04 \x01(\rR\aversion\"\x83\x01\n" +
+	"\aversion\x18\x04 \x01(\rR\aversion\x12.\n" +
+	"\asources\x18\x05 \x03(\v2\x14.dn42_map.DataSourceR\asources\"\x95\x01\n" +
+	"\n" +
+	"DataSource\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1a\n" +
+	"\brequired\x18\x02 \x01(\bR\brequired\x12\x1a\n" +
+	"\bincluded\x18\x03 \x01(\bR\bincluded\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x12%\n" +
+	"\x0edata_timestamp\x18\x05 \x01(\x04R\rdataTimestamp\"\xeb\x01\n" +
 	"\x05Graph\x12.\n" +
 	"\bmetadata\x18\x01 \x01(\v2\x12.dn42_map.MetadataR\bmetadata\x12$\n" +
 	"\x05nodes\x18\x02 \x03(\v2\x0e.dn42_map.NodeR\x05nodes\x12$\n" +
-	"\x05links\x18\x03 \x03(\v2\x0e.dn42_map.LinkR\x05linksB$Z\"github.com/iedon/dn42_map_go/protob\x06proto3"
+	"\x05links\x18\x03 \x03(\v2\x0e.dn42_map.LinkR\x05links\x12$\n" +
+	"\x05peers\x18\x04 \x03(\v2\x0e.dn42_map.PeerR\x05peers\x12@\n" +
+	"\x0fwithdrawn_links\x18\x05 \x03(\v2\x17.dn42_map.WithdrawnLinkR\x0ewithdrawnLinks*\x8d\x01\n" +
+	"\fRelationship\x12\x18\n" +
+	"\x14RELATIONSHIP_UNKNOWN\x10\x00\x12%\n" +
+	"!RELATIONSHIP_PROVIDER_TO_CUSTOMER\x10\x01\x12%\n" +
+	"!RELATIONSHIP_CUSTOMER_TO_PROVIDER\x10\x02\x12\x15\n" +
+	"\x11RELATIONSHIP_PEER\x10\x03B$Z\"github.com/iedon/dn42_map_go/protob\x06proto3"
 
 var (
 	file_graph_proto_rawDescOnce sync.Once
@@ -582,29 +1430,47 @@ func file_graph_proto_rawDescGZIP() []byte {
 	return file_graph_proto_rawDescData
 }
 
-var file_graph_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_graph_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_graph_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
 var file_graph_proto_goTypes = []any{
-	(*Node)(nil),       // 0: dn42_map.Node
-	(*Centrality)(nil), // 1: dn42_map.Centrality
-	(*Route)(nil),      // 2: dn42_map.Route
-	(*IPv6)(nil),       // 3: dn42_map.IPv6
-	(*Link)(nil),       // 4: dn42_map.Link
-	(*Metadata)(nil),   // 5: dn42_map.Metadata
-	(*Graph)(nil),      // 6: dn42_map.Graph
+	(Relationship)(0),       // 0: dn42_map.Relationship
+	(*Node)(nil),            // 1: dn42_map.Node
+	(*CustomerCone)(nil),    // 2: dn42_map.CustomerCone
+	(*RouteValidation)(nil), // 3: dn42_map.RouteValidation
+	(*LargeCommunity)(nil),  // 4: dn42_map.LargeCommunity
+	(*Centrality)(nil),      // 5: dn42_map.Centrality
+	(*Route)(nil),           // 6: dn42_map.Route
+	(*IPv6)(nil),            // 7: dn42_map.IPv6
+	(*Link)(nil),            // 8: dn42_map.Link
+	(*WithdrawnLink)(nil),   // 9: dn42_map.WithdrawnLink
+	(*Peer)(nil),            // 10: dn42_map.Peer
+	(*Metadata)(nil),        // 11: dn42_map.Metadata
+	(*DataSource)(nil),      // 12: dn42_map.DataSource
+	(*Graph)(nil),           // 13: dn42_map.Graph
 }
 var file_graph_proto_depIdxs = []int32{
-	2, // 0: dn42_map.Node.routes:type_name -> dn42_map.Route
-	1, // 1: dn42_map.Node.centrality:type_name -> dn42_map.Centrality
-	2, // 2: dn42_map.Node.routes_multicast:type_name -> dn42_map.Route
-	3, // 3: dn42_map.Route.ipv6:type_name -> dn42_map.IPv6
-	5, // 4: dn42_map.Graph.metadata:type_name -> dn42_map.Metadata
-	0, // 5: dn42_map.Graph.nodes:type_name -> dn42_map.Node
-	4, // 6: dn42_map.Graph.links:type_name -> dn42_map.Link
-	7, // [7:7] is the sub-list for method output_type
-	7, // [7:7] is the sub-list for method input_type
-	7, // [7:7] is the sub-list for extension type_name
-	7, // [7:7] is the sub-list for extension extendee
-	0, // [0:7] is the sub-list for field type_name
+	6,  // 0: dn42_map.Node.routes:type_name -> dn42_map.Route
+	5,  // 1: dn42_map.Node.centrality:type_name -> dn42_map.Centrality
+	6,  // 2: dn42_map.Node.routes_multicast:type_name -> dn42_map.Route
+	4,  // 3: dn42_map.Node.large_communities:type_name -> dn42_map.LargeCommunity
+	2,  // 4: dn42_map.Node.customer_cone:type_name -> dn42_map.CustomerCone
+	5,  // 5: dn42_map.Node.centrality_ipv4:type_name -> dn42_map.Centrality
+	5,  // 6: dn42_map.Node.centrality_ipv6:type_name -> dn42_map.Centrality
+	5,  // 7: dn42_map.Node.centrality_multicast:type_name -> dn42_map.Centrality
+	3,  // 8: dn42_map.Node.route_validation:type_name -> dn42_map.RouteValidation
+	7,  // 9: dn42_map.Route.ipv6:type_name -> dn42_map.IPv6
+	0,  // 10: dn42_map.Link.relationship:type_name -> dn42_map.Relationship
+	12, // 11: dn42_map.Metadata.sources:type_name -> dn42_map.DataSource
+	11, // 12: dn42_map.Graph.metadata:type_name -> dn42_map.Metadata
+	1,  // 13: dn42_map.Graph.nodes:type_name -> dn42_map.Node
+	8,  // 14: dn42_map.Graph.links:type_name -> dn42_map.Link
+	10, // 15: dn42_map.Graph.peers:type_name -> dn42_map.Peer
+	9,  // 16: dn42_map.Graph.withdrawn_links:type_name -> dn42_map.WithdrawnLink
+	17, // [17:17] is the sub-list for method output_type
+	17, // [17:17] is the sub-list for method input_type
+	17, // [17:17] is the sub-list for extension type_name
+	17, // [17:17] is the sub-list for extension extendee
+	0,  // [0:17] is the sub-list for field type_name
 }
 
 func init() { file_graph_proto_init() }
@@ -612,7 +1478,7 @@ func file_graph_proto_init() {
 	if File_graph_proto != nil {
 		return
 	}
-	file_graph_proto_msgTypes[2].OneofWrappers = []any{
+	file_graph_proto_msgTypes[5].OneofWrappers = []any{
 		(*Route_Ipv4)(nil),
 		(*Route_Ipv6)(nil),
 	}
@@ -621,13 +1487,14 @@ func file_graph_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_graph_proto_rawDesc), len(file_graph_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   7,
+			NumEnums:      1,
+			NumMessages:   13,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
 		GoTypes:           file_graph_proto_goTypes,
 		DependencyIndexes: file_graph_proto_depIdxs,
+		EnumInfos:         file_graph_proto_enumTypes,
 		MessageInfos:      file_graph_proto_msgTypes,
 	}.Build()
 	File_graph_proto = out.File
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/iedon/dn42_map_go/centrality"
+	pb "github.com/iedon/dn42_map_go/proto"
+)
+
+// defaultRankingProfile is the profile the map is generated with
+const defaultRankingProfile = "default"
+
+// builtinRankingProfiles are available without configuration
+var builtinRankingProfiles = map[string]centrality.Weights{
+	"transit-heavy": {Betweenness: 0.8, Closeness: 0.1, Degree: 0.1},
+	"degree-only":   {Degree: 1},
+}
+
+// rankingProfiles returns the dn42Index weights of all ranking profiles,
+// configured profiles override the built-in ones
+func rankingProfiles(config *Config) map[string]centrality.Weights {
+	profiles := maps.Clone(builtinRankingProfiles)
+	for name, weights := range config.Ranking.Profiles {
+		profiles[name] = weights
+	}
+
+	profiles[defaultRankingProfile] = centrality.DefaultWeights
+	if !config.Ranking.Weights.IsZero() {
+		profiles[defaultRankingProfile] = config.Ranking.Weights
+	}
+	return profiles
+}
+
+// rankingProfileNames returns the sorted names of all ranking profiles
+func (s *Server) rankingProfileNames() []string {
+	return slices.Sorted(maps.Keys(s.profiles))
+}
+
+// rankingAFs select the centrality /ranking?af= ranks by, the empty name
+// selects the combined graph
+var rankingAFs = map[string]func(node *pb.Node) *pb.Centrality{
+	"":          func(node *pb.Node) *pb.Centrality { return node.Centrality },
+	"ipv4":      func(node *pb.Node) *pb.Centrality { return node.CentralityIpv4 },
+	"ipv6":      func(node *pb.Node) *pb.Centrality { return node.CentralityIpv6 },
+	"multicast": func(node *pb.Node) *pb.Centrality { return node.CentralityMulticast },
+}
+
+// rankingSortKeys are the metrics /ranking can be sorted by besides the index,
+// in descending order
+var rankingSortKeys = map[string]func(node rankedNode) float64{
+	"degree":      func(node rankedNode) float64 { return node.Centrality.Degree },
+	"betweenness": func(node rankedNode) float64 { return node.Centrality.Betweenness },
+	"closeness":   func(node rankedNode) float64 { return node.Centrality.Closeness },
+	"harmonic":    func(node rankedNode) float64 { return node.Centrality.Harmonic },
+	"pagerank":    func(node rankedNode) float64 { return node.Centrality.Pagerank },
+	"eigenvector": func(node rankedNode) float64 { return node.Centrality.Eigenvector },
+
+	"in_betweenness":  func(node rankedNode) float64 { return node.Centrality.InBetweenness },
+	"out_betweenness": func(node rankedNode) float64 { return node.Centrality.OutBetweenness },
+
+	"customer_cone":          func(node rankedNode) float64 { return float64(node.CustomerCone.GetAsns()) },
+	"customer_cone_prefixes": func(node rankedNode) float64 { return float64(node.CustomerCone.GetPrefixes()) },
+}
+
+// rankedNode is a node with its dn42Index and ranking under a profile.
+// Centrality is the selected address family's and shadows the combined one.
+type rankedNode struct {
+	*pb.Node
+	Centrality *pb.Centrality
+	Index      uint32
+	Ranking    uint32
+}
+
+// storedRanking returns the nodes with the centrality selected by af in the
+// ranking stored in the map, which uses the default profile
+func storedRanking(nodes []*pb.Node, af func(node *pb.Node) *pb.Centrality) []rankedNode {
+	ranked := make([]rankedNode, 0, len(nodes))
+	for _, node := range nodes {
+		if c := af(node); c != nil {
+			ranked = append(ranked, rankedNode{Node: node, Centrality: c, Index: c.Index, Ranking: c.Ranking})
+		}
+	}
+	slices.SortFunc(ranked, func(a, b rankedNode) int {
+		return int(a.Ranking) - int(b.Ranking)
+	})
+	return ranked
+}
+
+// rankNodes ranks nodes by the dn42Index with the given weights, computed
+// from the centrality selected by af stored in the map
+func rankNodes(nodes []*pb.Node, af func(node *pb.Node) *pb.Centrality, weights centrality.Weights) []rankedNode {
+	cnodes := make([]*centrality.Node, 0, len(nodes))
+	byASN := make(map[uint32]*pb.Node, len(nodes))
+	for _, node := range nodes {
+		c := af(node)
+		if c == nil {
+			continue
+		}
+		cnodes = append(cnodes, &centrality.Node{
+			ASN:         node.Asn,
+			Degree:      c.Degree,
+			Betweenness: c.Betweenness,
+			Closeness:   c.Closeness,
+		})
+		byASN[node.Asn] = node
+	}
+
+	centrality.Rank(cnodes, weights)
+
+	ranked := make([]rankedNode, len(cnodes))
+	for i, cn := range cnodes {
+		node := byASN[cn.ASN]
+		ranked[i] = rankedNode{Node: node, Centrality: af(node), Index: cn.Index, Ranking: cn.Ranking}
+	}
+	return ranked
+}
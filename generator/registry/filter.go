@@ -0,0 +1,115 @@
+package registry
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// filterFiles are the prefix filters of the registry for IPv4 and IPv6
+var filterFiles = []string{"filter.txt", "filter6.txt"}
+
+// Filter is a rule of the registry's prefix filters, which limit the
+// prefixes and lengths route objects can authorize
+type Filter struct {
+	Number    int
+	Permit    bool
+	Prefix    netip.Prefix
+	MinLength int
+	MaxLength int
+}
+
+// loadFilters parses the prefix filters in the data directory, missing
+// filter files are skipped
+func (r *Registry) loadFilters(dataPath string) error {
+	for _, name := range filterFiles {
+		file, err := os.Open(filepath.Join(dataPath, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %v", name, err)
+		}
+
+		scanner := bufio.NewScanner(file)
+		for lineNo := 1; scanner.Scan(); lineNo++ {
+			line, _, _ := strings.Cut(scanner.Text(), "#")
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			filter, err := parseFilter(fields)
+			if err != nil {
+				r.errors = append(r.errors, fmt.Errorf("%s line %d: %v", name, lineNo, err))
+				continue
+			}
+			r.filters = append(r.filters, filter)
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", name, err)
+		}
+	}
+
+	slices.SortStableFunc(r.filters, func(a, b Filter) int {
+		return cmp.Compare(a.Number, b.Number)
+	})
+	return nil
+}
+
+// parseFilter parses the fields of a filter rule: number, permit or deny,
+// prefix, minimum and maximum length
+func parseFilter(fields []string) (Filter, error) {
+	if len(fields) < 5 {
+		return Filter{}, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+	number, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return Filter{}, fmt.Errorf("invalid number %q", fields[0])
+	}
+	if fields[1] != "permit" && fields[1] != "deny" {
+		return Filter{}, fmt.Errorf("invalid action %q", fields[1])
+	}
+	prefix, err := netip.ParsePrefix(fields[2])
+	if err != nil {
+		return Filter{}, fmt.Errorf("invalid prefix: %v", err)
+	}
+	minLength, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return Filter{}, fmt.Errorf("invalid minimum length %q", fields[3])
+	}
+	maxLength, err := strconv.Atoi(fields[4])
+	if err != nil {
+		return Filter{}, fmt.Errorf("invalid maximum length %q", fields[4])
+	}
+	return Filter{
+		Number:    number,
+		Permit:    fields[1] == "permit",
+		Prefix:    prefix.Masked(),
+		MinLength: minLength,
+		MaxLength: maxLength,
+	}, nil
+}
+
+// Filters returns the prefix filter rules ordered by number
+func (r *Registry) Filters() []Filter {
+	return r.filters
+}
+
+// MatchFilter returns the first filter rule covering prefix, or nil
+func (r *Registry) MatchFilter(prefix netip.Prefix) *Filter {
+	for i, filter := range r.filters {
+		if filter.Prefix.Addr().BitLen() == prefix.Addr().BitLen() &&
+			filter.Prefix.Bits() <= prefix.Bits() && filter.Prefix.Contains(prefix.Addr()) {
+			return &r.filters[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package registry
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Common holds the attributes and references shared by the object classes
+type Common struct {
+	Descr   []string
+	Remarks []string
+	MntBy   []string
+	AdminC  []string
+	TechC   []string
+	Org     string
+	Source  string
+
+	// References resolved after loading, objects missing from the registry are left out
+	Maintainers   []*Mntner
+	AdminContacts []*Contact
+	TechContacts  []*Contact
+	Organisation  *Organisation
+
+	Object *Object // Parsed object with all attributes
+}
+
+func newCommon(o *Object) Common {
+	return Common{
+		Descr:   o.GetAll("descr"),
+		Remarks: o.GetAll("remarks"),
+		MntBy:   o.GetList("mnt-by"),
+		AdminC:  o.GetList("admin-c"),
+		TechC:   o.GetList("tech-c"),
+		Org:     o.Get("org"),
+		Source:  o.Get("source"),
+		Object:  o,
+	}
+}
+
+// AutNum is an aut-num object
+type AutNum struct {
+	Common
+	ASN      uint32
+	ASName   string
+	MemberOf []string
+	Import   []string
+	Export   []string
+	MPImport []string
+	MPExport []string
+}
+
+// Mntner is a mntner object
+type Mntner struct {
+	Common
+	Name string
+	Auth []string
+}
+
+// Contact is a person or role object, both are referenced by their nic-hdl
+type Contact struct {
+	Common
+	Handle         string
+	Name           string
+	IsRole         bool
+	Email          []string
+	Contact        []string
+	PGPFingerprint string
+}
+
+// Inetnum is an inetnum or inet6num object
+type Inetnum struct {
+	Common
+	Prefix  netip.Prefix
+	NetName string
+	Country string
+	Status  string
+	Policy  string
+	NServer []string
+}
+
+// Route is a route or route6 object
+type Route struct {
+	Common
+	Prefix    netip.Prefix
+	Origins   []uint32
+	MaxLength int // 0 if not set
+	MemberOf  []string
+}
+
+// MaxPrefixLength returns the longest prefix length the route may be
+// announced with, the prefix length itself without max-length
+func (r *Route) MaxPrefixLength() int {
+	if r.MaxLength > 0 {
+		return r.MaxLength
+	}
+	return r.Prefix.Bits()
+}
+
+// ASSet is an as-set object
+type ASSet struct {
+	Common
+	Name      string
+	Members   []string
+	MbrsByRef []string
+}
+
+// ASBlock is an as-block object
+type ASBlock struct {
+	Common
+	Start  uint32
+	End    uint32
+	Policy string
+}
+
+// Organisation is an organisation object
+type Organisation struct {
+	Common
+	Handle string
+	Name   string
+	Email  []string
+}
+
+// ParseASN parses an ASN in the AS<number> form used by the registry
+func ParseASN(s string) (uint32, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 3 || !strings.EqualFold(s[:2], "AS") {
+		return 0, fmt.Errorf("invalid ASN %q", s)
+	}
+	asn, err := strconv.ParseUint(s[2:], 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ASN %q", s)
+	}
+	return uint32(asn), nil
+}
+
+func newAutNum(o *Object) (*AutNum, error) {
+	asn, err := ParseASN(o.Key())
+	if err != nil {
+		return nil, err
+	}
+	return &AutNum{
+		Common:   newCommon(o),
+		ASN:      asn,
+		ASName:   o.Get("as-name"),
+		MemberOf: o.GetList("member-of"),
+		Import:   o.GetAll("import"),
+		Export:   o.GetAll("export"),
+		MPImport: o.GetAll("mp-import"),
+		MPExport: o.GetAll("mp-export"),
+	}, nil
+}
+
+func newMntner(o *Object) (*Mntner, error) {
+	return &Mntner{
+		Common: newCommon(o),
+		Name:   o.Key(),
+		Auth:   o.GetAll("auth"),
+	}, nil
+}
+
+func newContact(o *Object) (*Contact, error) {
+	handle := o.Get("nic-hdl")
+	if handle == "" {
+		return nil, fmt.Errorf("%s %q has no nic-hdl", o.Class(), o.Key())
+	}
+	return &Contact{
+		Common:         newCommon(o),
+		Handle:         handle,
+		Name:           o.Key(),
+		IsRole:         o.Class() == "role",
+		Email:          o.GetAll("e-mail"),
+		Contact:        o.GetAll("contact"),
+		PGPFingerprint: o.Get("pgp-fingerprint"),
+	}, nil
+}
+
+func newInetnum(o *Object) (*Inetnum, error) {
+	prefix, err := netip.ParsePrefix(o.Get("cidr"))
+	if err != nil {
+		return nil, fmt.Errorf("%s %q has an invalid cidr: %v", o.Class(), o.Key(), err)
+	}
+	return &Inetnum{
+		Common:  newCommon(o),
+		Prefix:  prefix.Masked(),
+		NetName: o.Get("netname"),
+		Country: o.Get("country"),
+		Status:  o.Get("status"),
+		Policy:  o.Get("policy"),
+		NServer: o.GetAll("nserver"),
+	}, nil
+}
+
+func newRoute(o *Object) (*Route, error) {
+	prefix, err := netip.ParsePrefix(o.Key())
+	if err != nil {
+		return nil, fmt.Errorf("%s %q has an invalid prefix: %v", o.Class(), o.Key(), err)
+	}
+	route := &Route{
+		Common:   newCommon(o),
+		Prefix:   prefix.Masked(),
+		MemberOf: o.GetList("member-of"),
+	}
+	for _, origin := range o.GetList("origin") {
+		asn, err := ParseASN(origin)
+		if err != nil {
+			return nil, fmt.Errorf("%s %q: %v", o.Class(), o.Key(), err)
+		}
+		route.Origins = append(route.Origins, asn)
+	}
+	if maxLength := o.Get("max-length"); maxLength != "" {
+		route.MaxLength, err = strconv.Atoi(maxLength)
+		if err != nil || route.MaxLength < prefix.Bits() || route.MaxLength > prefix.Addr().BitLen() {
+			return nil, fmt.Errorf("%s %q has an invalid max-length %q", o.Class(), o.Key(), maxLength)
+		}
+	}
+	return route, nil
+}
+
+func newASSet(o *Object) (*ASSet, error) {
+	return &ASSet{
+		Common:    newCommon(o),
+		Name:      o.Key(),
+		Members:   o.GetList("members"),
+		MbrsByRef: o.GetList("mbrs-by-ref"),
+	}, nil
+}
+
+func newASBlock(o *Object) (*ASBlock, error) {
+	start, end, ok := strings.Cut(o.Key(), "-")
+	if !ok {
+		return nil, fmt.Errorf("as-block %q is not a range", o.Key())
+	}
+	startASN, err := ParseASN(start)
+	if err != nil {
+		return nil, fmt.Errorf("as-block %q: %v", o.Key(), err)
+	}
+	endASN, err := ParseASN(end)
+	if err != nil {
+		return nil, fmt.Errorf("as-block %q: %v", o.Key(), err)
+	}
+	return &ASBlock{
+		Common: newCommon(o),
+		Start:  startASN,
+		End:    endASN,
+		Policy: o.Get("policy"),
+	}, nil
+}
+
+func newOrganisation(o *Object) (*Organisation, error) {
+	return &Organisation{
+		Common: newCommon(o),
+		Handle: o.Key(),
+		Name:   o.Get("org-name"),
+		Email:  o.GetAll("e-mail"),
+	}, nil
+}
@@ -1,98 +1,389 @@
 package registry
 
 import (
-	"bufio"
+	"bytes"
+	"cmp"
 	"fmt"
+	"net/netip"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
-	"sync"
 )
 
+// objectClasses are the directories under data/ that are loaded
+var objectClasses = []string{
+	"aut-num", "mntner", "person", "role", "inetnum", "inet6num",
+	"route", "route6", "as-set", "as-block", "organisation",
+}
+
+// Registry is a parsed DN42 registry. Names and handles are looked up
+// case-insensitively.
 type Registry struct {
-	basePath string
-	cache    sync.Map
+	autNums       map[uint32]*AutNum
+	mntners       map[string]*Mntner
+	contacts      map[string]*Contact // person and role objects by nic-hdl
+	organisations map[string]*Organisation
+	asSets        map[string]*ASSet
+	asBlocks      []*ASBlock // Sorted by first ASN
+	inetnums      []*Inetnum // inetnum and inet6num, sorted by prefix
+	routes        []*Route   // route and route6, sorted by prefix
+	filters       []Filter   // Prefix filter rules ordered by number
+	objects       []*Common  // Every loaded object, for resolving references
+	errors        []error    // Objects skipped because they could not be parsed
 }
 
-// NewRegistry creates a new registry processor
-func NewRegistry(basePath string) *Registry {
+// New creates an empty registry
+func New() *Registry {
 	return &Registry{
-		basePath: basePath,
+		autNums:       make(map[uint32]*AutNum),
+		mntners:       make(map[string]*Mntner),
+		contacts:      make(map[string]*Contact),
+		organisations: make(map[string]*Organisation),
+		asSets:        make(map[string]*ASSet),
 	}
 }
 
-// GetDescriptions concurrently gets descriptions for multiple ASNs
-func (r *Registry) GetDescriptions(asns map[uint32]struct{}) map[uint32]string {
-	results := make(map[uint32]string)
-	var wg sync.WaitGroup
-	var mu sync.Mutex
+// Load parses the objects in the data directory of a registry checkout.
+// Objects that cannot be parsed are skipped and reported by Errors.
+func Load(basePath string) (*Registry, error) {
+	dataPath := filepath.Join(basePath, "data")
+	if _, err := os.Stat(dataPath); err != nil {
+		return nil, fmt.Errorf("failed to open registry: %v", err)
+	}
 
-	for asn := range asns {
-		wg.Add(1)
-		go func(asn uint32) {
-			defer wg.Done()
-			desc := r.getDescByASN(asn)
-			mu.Lock()
-			results[asn] = desc
-			mu.Unlock()
-		}(asn)
+	r := New()
+	for _, class := range objectClasses {
+		entries, err := os.ReadDir(filepath.Join(dataPath, class))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s objects: %v", class, err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			path := filepath.Join(dataPath, class, entry.Name())
+			if err := r.loadFile(path); err != nil {
+				r.errors = append(r.errors, fmt.Errorf("%s: %v", path, err))
+			}
+		}
 	}
 
-	wg.Wait()
-	return results
+	slices.SortFunc(r.asBlocks, func(a, b *ASBlock) int {
+		return cmp.Compare(a.Start, b.Start)
+	})
+	slices.SortFunc(r.inetnums, func(a, b *Inetnum) int {
+		return comparePrefixes(a.Prefix, b.Prefix)
+	})
+	slices.SortFunc(r.routes, func(a, b *Route) int {
+		return comparePrefixes(a.Prefix, b.Prefix)
+	})
+	r.resolve()
+
+	if err := r.loadFilters(dataPath); err != nil {
+		return nil, err
+	}
+	return r, nil
 }
 
-// getDescByASN gets the description for a single ASN
-func (r *Registry) getDescByASN(asn uint32) string {
-	// Check cache
-	if desc, ok := r.cache.Load(asn); ok {
-		return desc.(string)
+// loadFile parses a registry file and adds its objects
+func (r *Registry) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
 	}
 
-	filePath := filepath.Join(r.basePath, "data", "aut-num", fmt.Sprintf("AS%d", asn))
-	file, err := os.Open(filePath)
+	// Malformed lines are recorded, the rest of their object is still loaded
+	objects, err := Parse(bytes.NewReader(data))
 	if err != nil {
-		defaultDesc := fmt.Sprintf("AS%d", asn)
-		r.cache.Store(asn, defaultDesc)
-		return defaultDesc
-	}
-	defer file.Close()
-
-	var (
-		adminC string
-		mntr   string
-		asName string
-		descr  string
-	)
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "admin-c:") {
-			adminC = strings.TrimSpace(strings.TrimPrefix(line, "admin-c:"))
-		} else if strings.HasPrefix(line, "mnt-by:") {
-			mntr = strings.TrimSpace(strings.TrimPrefix(line, "mnt-by:"))
-		} else if strings.HasPrefix(line, "as-name:") {
-			asName = strings.TrimSpace(strings.TrimPrefix(line, "as-name:"))
-		} else if strings.HasPrefix(line, "descr:") {
-			descr = strings.TrimSpace(strings.TrimPrefix(line, "descr:"))
-		}
-	}
-
-	var result string
-	switch {
-	case adminC != "" && adminC == mntr:
-		result = adminC
-	case adminC != "":
-		result = adminC
-	case asName != "":
-		result = asName
-	case descr != "":
-		result = descr
+		r.errors = append(r.errors, fmt.Errorf("%s: %v", path, err))
+	}
+	if len(objects) == 0 {
+		return fmt.Errorf("no object found")
+	}
+	// Invalid objects are recorded, the other objects of the file are still added
+	raw := string(data)
+	for _, o := range objects {
+		o.Raw = raw
+		if err := r.add(o); err != nil {
+			r.errors = append(r.errors, fmt.Errorf("%s: %v", path, err))
+			continue
+		}
+	}
+	return nil
+}
+
+// add adds a parsed object, objects of other classes are ignored
+func (r *Registry) add(o *Object) error {
+	var common *Common
+	switch o.Class() {
+	case "aut-num":
+		autNum, err := newAutNum(o)
+		if err != nil {
+			return err
+		}
+		r.autNums[autNum.ASN] = autNum
+		common = &autNum.Common
+	case "mntner":
+		mntner, err := newMntner(o)
+		if err != nil {
+			return err
+		}
+		r.mntners[strings.ToUpper(mntner.Name)] = mntner
+		common = &mntner.Common
+	case "person", "role":
+		contact, err := newContact(o)
+		if err != nil {
+			return err
+		}
+		r.contacts[strings.ToUpper(contact.Handle)] = contact
+		common = &contact.Common
+	case "organisation":
+		org, err := newOrganisation(o)
+		if err != nil {
+			return err
+		}
+		r.organisations[strings.ToUpper(org.Handle)] = org
+		common = &org.Common
+	case "as-set":
+		set, err := newASSet(o)
+		if err != nil {
+			return err
+		}
+		r.asSets[strings.ToUpper(set.Name)] = set
+		common = &set.Common
+	case "as-block":
+		block, err := newASBlock(o)
+		if err != nil {
+			return err
+		}
+		r.asBlocks = append(r.asBlocks, block)
+		common = &block.Common
+	case "inetnum", "inet6num":
+		inetnum, err := newInetnum(o)
+		if err != nil {
+			return err
+		}
+		r.inetnums = append(r.inetnums, inetnum)
+		common = &inetnum.Common
+	case "route", "route6":
+		route, err := newRoute(o)
+		if err != nil {
+			return err
+		}
+		r.routes = append(r.routes, route)
+		common = &route.Common
 	default:
-		result = fmt.Sprintf("AS%d", asn)
+		return nil
+	}
+	r.objects = append(r.objects, common)
+	return nil
+}
+
+// resolve links the maintainers, contacts and organisations referenced by
+// every object
+func (r *Registry) resolve() {
+	for _, c := range r.objects {
+		for _, name := range c.MntBy {
+			if mntner := r.Mntner(name); mntner != nil {
+				c.Maintainers = append(c.Maintainers, mntner)
+			}
+		}
+		for _, handle := range c.AdminC {
+			if contact := r.Contact(handle); contact != nil {
+				c.AdminContacts = append(c.AdminContacts, contact)
+			}
+		}
+		for _, handle := range c.TechC {
+			if contact := r.Contact(handle); contact != nil {
+				c.TechContacts = append(c.TechContacts, contact)
+			}
+		}
+		if c.Org != "" {
+			c.Organisation = r.Organisation(c.Org)
+		}
+	}
+}
+
+// Errors returns the errors of the objects, lines and filter rules skipped while loading
+func (r *Registry) Errors() []error {
+	return r.errors
+}
+
+// AutNum returns the aut-num object of an ASN, or nil if it is not registered
+func (r *Registry) AutNum(asn uint32) *AutNum {
+	return r.autNums[asn]
+}
+
+// Mntner returns a mntner object by name, or nil if it is not registered
+func (r *Registry) Mntner(name string) *Mntner {
+	return r.mntners[strings.ToUpper(name)]
+}
+
+// Contact returns a person or role object by nic-hdl, or nil if it is not registered
+func (r *Registry) Contact(handle string) *Contact {
+	return r.contacts[strings.ToUpper(handle)]
+}
+
+// Organisation returns an organisation object by handle, or nil if it is not registered
+func (r *Registry) Organisation(handle string) *Organisation {
+	return r.organisations[strings.ToUpper(handle)]
+}
+
+// ASSet returns an as-set object by name, or nil if it is not registered
+func (r *Registry) ASSet(name string) *ASSet {
+	return r.asSets[strings.ToUpper(name)]
+}
+
+// ASBlock returns the as-block containing an ASN, or nil if there is none
+func (r *Registry) ASBlock(asn uint32) *ASBlock {
+	var found *ASBlock
+	for _, block := range r.asBlocks {
+		if block.Start > asn {
+			break
+		}
+		// Prefer the most specific of nested blocks
+		if asn <= block.End && (found == nil || block.End-block.Start < found.End-found.Start) {
+			found = block
+		}
+	}
+	return found
+}
+
+// Inetnums returns the inetnum and inet6num objects sorted by prefix
+func (r *Registry) Inetnums() []*Inetnum {
+	return r.inetnums
+}
+
+// Routes returns the route and route6 objects sorted by prefix
+func (r *Registry) Routes() []*Route {
+	return r.routes
+}
+
+// ExpandASSet returns the sorted ASNs of an as-set, including nested sets
+// and aut-nums joining it with member-of as allowed by its mbrs-by-ref
+func (r *Registry) ExpandASSet(name string) []uint32 {
+	seen := make(map[string]bool)
+	members := make(map[uint32]struct{})
+
+	var expand func(name string)
+	expand = func(name string) {
+		key := strings.ToUpper(name)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		set := r.asSets[key]
+		if set == nil {
+			return
+		}
+
+		for _, member := range set.Members {
+			if asn, err := ParseASN(member); err == nil {
+				members[asn] = struct{}{}
+			} else {
+				expand(member)
+			}
+		}
+		if len(set.MbrsByRef) == 0 {
+			return
+		}
+		for asn, autNum := range r.autNums {
+			if containsFold(autNum.MemberOf, set.Name) && memberByRef(set.MbrsByRef, autNum.MntBy) {
+				members[asn] = struct{}{}
+			}
+		}
+	}
+	expand(name)
+
+	asns := make([]uint32, 0, len(members))
+	for asn := range members {
+		asns = append(asns, asn)
 	}
+	slices.Sort(asns)
+	return asns
+}
 
-	r.cache.Store(asn, result)
-	return result
+// memberByRef reports whether an object maintained by mntBy may join a set
+// with the given mbrs-by-ref
+func memberByRef(mbrsByRef, mntBy []string) bool {
+	if containsFold(mbrsByRef, "ANY") {
+		return true
+	}
+	for _, mntner := range mntBy {
+		if containsFold(mbrsByRef, mntner) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	return slices.ContainsFunc(list, func(item string) bool {
+		return strings.EqualFold(item, s)
+	})
+}
+
+// Description returns the display name of an ASN: the last admin-c of its
+// aut-num, its as-name or its last descr, or AS<asn> if it is not registered
+func (r *Registry) Description(asn uint32) string {
+	autNum := r.autNums[asn]
+	if autNum == nil {
+		return fmt.Sprintf("AS%d", asn)
+	}
+	if adminC := lastLine(autNum.Object, "admin-c"); adminC != "" {
+		return adminC
+	}
+	if asName := lastLine(autNum.Object, "as-name"); asName != "" {
+		return asName
+	}
+	if descr := lastLine(autNum.Object, "descr"); descr != "" {
+		return descr
+	}
+	return fmt.Sprintf("AS%d", asn)
+}
+
+// lastLine returns the first line of the last value of an attribute, the
+// value a line by line scan of the object file ends up with
+func lastLine(o *Object, name string) string {
+	values := o.GetAll(name)
+	if len(values) == 0 {
+		return ""
+	}
+	line, _, _ := strings.Cut(values[len(values)-1], "\n")
+	return line
+}
+
+// GetDescriptions gets descriptions for multiple ASNs
+func (r *Registry) GetDescriptions(asns map[uint32]struct{}) map[uint32]string {
+	results := make(map[uint32]string, len(asns))
+	for asn := range asns {
+		results[asn] = r.Description(asn)
+	}
+	return results
+}
+
+// Whois returns the aut-num object of an ASN as it is stored in the
+// registry, or "" if it is not registered
+func (r *Registry) Whois(asn uint32) string {
+	autNum := r.autNums[asn]
+	if autNum == nil {
+		return ""
+	}
+	if autNum.Object.Raw != "" {
+		return autNum.Object.Raw
+	}
+	return autNum.Object.String()
+}
+
+// comparePrefixes orders prefixes by address family, address and length
+func comparePrefixes(a, b netip.Prefix) int {
+	if c := a.Addr().Compare(b.Addr()); c != 0 {
+		return c
+	}
+	return a.Bits() - b.Bits()
 }
@@ -0,0 +1,208 @@
+package registry
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testRegistry writes files below the data directory of a temporary
+// registry checkout and loads it
+func testRegistry(t *testing.T, files map[string]string) *Registry {
+	t.Helper()
+	base := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(base, "data", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r, err := Load(base)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return r
+}
+
+func TestDescription(t *testing.T) {
+	r := testRegistry(t, map[string]string{
+		"aut-num/AS4242420001": "aut-num:            AS4242420001\n" +
+			"as-name:            FIRST-AS\n" +
+			"descr:              First\n" +
+			"admin-c:            ONE-DN42\n" +
+			"admin-c:            TWO-DN42\n",
+		"aut-num/AS4242420002": "aut-num:            AS4242420002\n" +
+			"as-name:            SECOND-AS\n" +
+			"descr:              Second\n",
+		"aut-num/AS4242420003": "aut-num:            AS4242420003\n" +
+			"descr:              Third\n" +
+			"descr:              Third, continued\n" +
+			"                    on a second line\n",
+		"aut-num/AS4242420004": "aut-num:            AS4242420004\n",
+	})
+
+	tests := []struct {
+		asn  uint32
+		want string
+	}{
+		{4242420001, "TWO-DN42"},
+		{4242420002, "SECOND-AS"},
+		{4242420003, "Third, continued"},
+		{4242420004, "AS4242420004"},
+		{4242420005, "AS4242420005"},
+	}
+	for _, tt := range tests {
+		if got := r.Description(tt.asn); got != tt.want {
+			t.Errorf("Description(%d) = %q, want %q", tt.asn, got, tt.want)
+		}
+	}
+}
+
+func TestWhois(t *testing.T) {
+	file := "aut-num:   AS4242420001\n" +
+		"as-name: EXAMPLE-AS   \n" +
+		"% kept as it is\n" +
+		"remarks:            text\n"
+	r := testRegistry(t, map[string]string{"aut-num/AS4242420001": file})
+
+	if got := r.Whois(4242420001); got != file {
+		t.Errorf("Whois() = %q, want the unmodified file %q", got, file)
+	}
+	if got := r.Whois(4242420002); got != "" {
+		t.Errorf("Whois() of an unregistered ASN = %q", got)
+	}
+}
+
+func TestLoadSkipsInvalid(t *testing.T) {
+	r := testRegistry(t, map[string]string{
+		"route/172.20.0.0_24": "route:              not-a-prefix\n" +
+			"origin:             AS4242420001\n" +
+			"\n" +
+			"route:              172.20.0.0/24\n" +
+			"origin:             AS4242420001\n" +
+			"this line is malformed\n" +
+			"max-length:         28\n",
+		"route/172.20.1.0_24": "route:              172.20.1.0/24\n" +
+			"origin:             AS4242420001\n" +
+			"max-length:         16\n",
+		"route6/fd42:4242:1::_48": "route6:             fd42:4242:1::/48\n" +
+			"origin:             AS4242420001\n" +
+			"origin:             AS4242420002\n",
+		"aut-num/AS4242420001": "",
+	})
+
+	var prefixes []string
+	for _, route := range r.Routes() {
+		prefixes = append(prefixes, route.Prefix.String())
+	}
+	if want := []string{"172.20.0.0/24", "fd42:4242:1::/48"}; !reflect.DeepEqual(prefixes, want) {
+		t.Errorf("routes = %v, want %v", prefixes, want)
+	}
+	if r.Routes()[0].MaxPrefixLength() != 28 {
+		t.Errorf("max-length after a malformed line = %d, want 28", r.Routes()[0].MaxPrefixLength())
+	}
+	if origins := r.Routes()[1].Origins; !reflect.DeepEqual(origins, []uint32{4242420001, 4242420002}) {
+		t.Errorf("origins = %v", origins)
+	}
+	// Invalid prefix, malformed line, invalid max-length and the empty file
+	if len(r.Errors()) != 4 {
+		t.Errorf("Errors() = %v, want 4 errors", r.Errors())
+	}
+}
+
+func TestResolve(t *testing.T) {
+	r := testRegistry(t, map[string]string{
+		"aut-num/AS4242420001": "aut-num:            AS4242420001\n" +
+			"admin-c:            example-dn42\n" +
+			"mnt-by:             EXAMPLE-MNT\n" +
+			"mnt-by:             MISSING-MNT\n" +
+			"org:                ORG-EXAMPLE\n",
+		"mntner/EXAMPLE-MNT":       "mntner:             EXAMPLE-MNT\n",
+		"person/EXAMPLE-DN42":      "person:             Example\nnic-hdl:            EXAMPLE-DN42\n",
+		"organisation/ORG-EXAMPLE": "organisation:       ORG-EXAMPLE\norg-name:           Example\n",
+	})
+
+	autNum := r.AutNum(4242420001)
+	if autNum == nil {
+		t.Fatal("aut-num not loaded")
+	}
+	if len(autNum.Maintainers) != 1 || autNum.Maintainers[0] != r.Mntner("example-mnt") {
+		t.Errorf("maintainers = %v", autNum.Maintainers)
+	}
+	if len(autNum.AdminContacts) != 1 || autNum.AdminContacts[0] != r.Contact("EXAMPLE-DN42") {
+		t.Errorf("admin contacts = %v", autNum.AdminContacts)
+	}
+	if autNum.Organisation == nil || autNum.Organisation != r.Organisation("ORG-EXAMPLE") {
+		t.Errorf("organisation = %v", autNum.Organisation)
+	}
+}
+
+func TestExpandASSet(t *testing.T) {
+	r := testRegistry(t, map[string]string{
+		"as-set/AS-OUTER": "as-set:             AS-OUTER\n" +
+			"members:            AS4242420001, AS-INNER\n",
+		"as-set/AS-INNER": "as-set:             AS-INNER\n" +
+			"members:            AS4242420002 AS-OUTER\n" +
+			"mbrs-by-ref:        JOIN-MNT\n",
+		"aut-num/AS4242420003": "aut-num:            AS4242420003\n" +
+			"member-of:          AS-INNER\n" +
+			"mnt-by:             JOIN-MNT\n",
+		"aut-num/AS4242420004": "aut-num:            AS4242420004\n" +
+			"member-of:          AS-INNER\n" +
+			"mnt-by:             OTHER-MNT\n",
+	})
+
+	tests := []struct {
+		name string
+		want []uint32
+	}{
+		{"AS-OUTER", []uint32{4242420001, 4242420002, 4242420003}},
+		{"as-inner", []uint32{4242420001, 4242420002, 4242420003}},
+		{"AS-MISSING", []uint32{}},
+	}
+	for _, tt := range tests {
+		if got := r.ExpandASSet(tt.name); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ExpandASSet(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMatchFilter(t *testing.T) {
+	r := testRegistry(t, map[string]string{
+		"filter.txt": "# comment\n" +
+			"1 deny 172.20.0.0/24 24 32\n" +
+			"2 permit 172.20.0.0/14 21 29 # DN42\n" +
+			"invalid line\n" +
+			"99 deny 0.0.0.0/0 0 32\n",
+		"filter6.txt": "1 permit fd00::/8 44 64\n",
+	})
+
+	tests := []struct {
+		prefix string
+		want   int // Number of the matching rule, 0 for none
+	}{
+		{"172.20.0.0/24", 1},
+		{"172.20.0.0/28", 1},
+		{"172.20.1.0/24", 2},
+		{"10.0.0.0/8", 99},
+		{"fd42:4242:1::/48", 1},
+		{"2001:db8::/32", 0},
+	}
+	for _, tt := range tests {
+		got := 0
+		if filter := r.MatchFilter(netip.MustParsePrefix(tt.prefix)); filter != nil {
+			got = filter.Number
+		}
+		if got != tt.want {
+			t.Errorf("MatchFilter(%s) = rule %d, want %d", tt.prefix, got, tt.want)
+		}
+	}
+	if len(r.Errors()) != 1 {
+		t.Errorf("Errors() = %v, want the invalid rule", r.Errors())
+	}
+}
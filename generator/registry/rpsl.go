@@ -0,0 +1,159 @@
+package registry
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// rpslColumn is the column attribute values are aligned to in the registry
+const rpslColumn = 20
+
+// Attribute is a single RPSL attribute, continuation lines of its value are
+// joined with newlines
+type Attribute struct {
+	Name  string
+	Value string
+}
+
+// Object is a parsed RPSL object. Its first attribute gives the object class
+// and the primary key.
+type Object struct {
+	Attributes []Attribute
+	Raw        string // Unmodified text of the file the object was loaded from, if any
+}
+
+// Class returns the object class, e.g. aut-num
+func (o *Object) Class() string {
+	return o.Attributes[0].Name
+}
+
+// Key returns the primary key of the object, e.g. AS4242420000
+func (o *Object) Key() string {
+	return o.Attributes[0].Value
+}
+
+// Get returns the first value of an attribute, or "" if it is not set
+func (o *Object) Get(name string) string {
+	for _, attr := range o.Attributes {
+		if attr.Name == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// GetAll returns every value of a repeated attribute
+func (o *Object) GetAll(name string) []string {
+	var values []string
+	for _, attr := range o.Attributes {
+		if attr.Name == name {
+			values = append(values, attr.Value)
+		}
+	}
+	return values
+}
+
+// GetList returns the items of a list attribute such as members, which may
+// be repeated and hold several items separated by commas or whitespace
+func (o *Object) GetList(name string) []string {
+	var items []string
+	for _, value := range o.GetAll(name) {
+		items = append(items, strings.FieldsFunc(value, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\n'
+		})...)
+	}
+	return items
+}
+
+// String formats the object in the column layout of the registry
+func (o *Object) String() string {
+	var b strings.Builder
+	for _, attr := range o.Attributes {
+		width := max(rpslColumn, len(attr.Name)+2)
+		for i, line := range strings.Split(attr.Value, "\n") {
+			switch {
+			case i == 0:
+				b.WriteString(strings.TrimRight(fmt.Sprintf("%-*s%s", width, attr.Name+":", line), " "))
+			case line == "":
+				b.WriteString("+")
+			default:
+				fmt.Fprintf(&b, "%*s%s", width, "", line)
+			}
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// Parse parses the RPSL objects in r. Objects are separated by blank lines,
+// lines starting with whitespace or + continue the value of the previous
+// attribute and lines starting with % or # are comments. Malformed lines are
+// skipped, the objects are returned with an error listing them.
+func Parse(r io.Reader) ([]*Object, error) {
+	var objects []*Object
+	var current *Object
+	var skipped []error
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		switch {
+		case line == "":
+			if current != nil {
+				objects = append(objects, current)
+				current = nil
+			}
+		case line[0] == '%' || line[0] == '#':
+			continue
+		case line[0] == ' ' || line[0] == '\t' || line[0] == '+':
+			if current == nil {
+				skipped = append(skipped, fmt.Errorf("line %d: continuation without an attribute", lineNo))
+				continue
+			}
+			last := &current.Attributes[len(current.Attributes)-1]
+			continuation := strings.TrimSpace(line[1:])
+			if last.Value == "" {
+				last.Value = continuation
+			} else {
+				last.Value += "\n" + continuation
+			}
+		default:
+			name, value, ok := strings.Cut(line, ":")
+			if !ok || !isAttributeName(name) {
+				skipped = append(skipped, fmt.Errorf("line %d: expected an attribute, got %q", lineNo, line))
+				continue
+			}
+			if current == nil {
+				current = &Object{}
+			}
+			current.Attributes = append(current.Attributes, Attribute{
+				Name:  strings.ToLower(name),
+				Value: strings.TrimSpace(value),
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if current != nil {
+		objects = append(objects, current)
+	}
+	return objects, errors.Join(skipped...)
+}
+
+// isAttributeName reports whether s is a valid RPSL attribute name
+func isAttributeName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,157 @@
+package registry
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    [][]Attribute
+		skipped int // Number of malformed lines reported
+	}{
+		{
+			name: "single object",
+			input: "aut-num:            AS4242420001\n" +
+				"as-name:            EXAMPLE-AS\n" +
+				"mnt-by:             EXAMPLE-MNT\n",
+			want: [][]Attribute{{
+				{Name: "aut-num", Value: "AS4242420001"},
+				{Name: "as-name", Value: "EXAMPLE-AS"},
+				{Name: "mnt-by", Value: "EXAMPLE-MNT"},
+			}},
+		},
+		{
+			name: "continuation lines",
+			input: "person:             Example Person\n" +
+				"remarks:            first\n" +
+				"                    second\n" +
+				"+\n" +
+				"\tthird\n" +
+				"nic-hdl:            EXAMPLE-DN42\n",
+			want: [][]Attribute{{
+				{Name: "person", Value: "Example Person"},
+				{Name: "remarks", Value: "first\nsecond\n\nthird"},
+				{Name: "nic-hdl", Value: "EXAMPLE-DN42"},
+			}},
+		},
+		{
+			name: "continuation of an empty value",
+			input: "descr:\n" +
+				"                    text\n",
+			want: [][]Attribute{{{Name: "descr", Value: "text"}}},
+		},
+		{
+			name: "repeated attributes, comments and case",
+			input: "% comment\n" +
+				"Route:              172.20.0.0/24\n" +
+				"# comment\n" +
+				"origin:             AS4242420001\n" +
+				"origin:             AS4242420002\n",
+			want: [][]Attribute{{
+				{Name: "route", Value: "172.20.0.0/24"},
+				{Name: "origin", Value: "AS4242420001"},
+				{Name: "origin", Value: "AS4242420002"},
+			}},
+		},
+		{
+			name: "objects separated by blank lines",
+			input: "mntner:             A-MNT\n" +
+				"\n\n" +
+				"mntner:             B-MNT\r\n" +
+				"\n",
+			want: [][]Attribute{
+				{{Name: "mntner", Value: "A-MNT"}},
+				{{Name: "mntner", Value: "B-MNT"}},
+			},
+		},
+		{
+			name: "value containing colons",
+			input: "inet6num:           fd42:4242:0001:0000:0000:0000:0000:0000\n" +
+				"remarks:            see https://dn42.dev\n",
+			want: [][]Attribute{{
+				{Name: "inet6num", Value: "fd42:4242:0001:0000:0000:0000:0000:0000"},
+				{Name: "remarks", Value: "see https://dn42.dev"},
+			}},
+		},
+		{
+			name: "malformed lines are skipped",
+			input: "  continuation without an attribute\n" +
+				"aut-num:            AS4242420001\n" +
+				"this is not an attribute\n" +
+				"bad name:           value\n" +
+				"as-name:            EXAMPLE-AS\n",
+			want: [][]Attribute{{
+				{Name: "aut-num", Value: "AS4242420001"},
+				{Name: "as-name", Value: "EXAMPLE-AS"},
+			}},
+			skipped: 3,
+		},
+		{
+			name:  "empty",
+			input: "% only a comment\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects, err := Parse(strings.NewReader(tt.input))
+			var got [][]Attribute
+			for _, o := range objects {
+				got = append(got, o.Attributes)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+
+			skipped := 0
+			if err != nil {
+				skipped = len(strings.Split(err.Error(), "\n"))
+			}
+			if skipped != tt.skipped {
+				t.Errorf("Parse() reported %d malformed lines, want %d: %v", skipped, tt.skipped, err)
+			}
+		})
+	}
+}
+
+func TestObjectString(t *testing.T) {
+	input := "aut-num:            AS4242420001\n" +
+		"descr:              first\n" +
+		"                    second\n" +
+		"+\n" +
+		"                    third\n" +
+		"mp-import:          afi ipv6.unicast from AS4242420002 accept ANY\n"
+
+	objects, err := Parse(strings.NewReader(input))
+	if err != nil || len(objects) != 1 {
+		t.Fatalf("Parse() = %d objects, %v", len(objects), err)
+	}
+	if got := objects[0].String(); got != input {
+		t.Errorf("String() = %q, want %q", got, input)
+	}
+}
+
+func TestObjectGetList(t *testing.T) {
+	objects, err := Parse(strings.NewReader("as-set:             AS-EXAMPLE\n" +
+		"members:            AS4242420001, AS4242420002\n" +
+		"                    AS4242420003\n" +
+		"members:            AS-OTHER\tAS4242420004\n"))
+	if err != nil || len(objects) != 1 {
+		t.Fatalf("Parse() = %d objects, %v", len(objects), err)
+	}
+
+	want := []string{"AS4242420001", "AS4242420002", "AS4242420003", "AS-OTHER", "AS4242420004"}
+	if got := objects[0].GetList("members"); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetList() = %v, want %v", got, want)
+	}
+	if got := objects[0].Get("members"); got != "AS4242420001, AS4242420002\nAS4242420003" {
+		t.Errorf("Get() = %q", got)
+	}
+	if got := objects[0].Get("descr"); got != "" {
+		t.Errorf("Get() of a missing attribute = %q", got)
+	}
+}
@@ -0,0 +1,57 @@
+package roa
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// jsonTrustAnchor is the trust anchor name of the ROAs in the JSON export
+const jsonTrustAnchor = "dn42"
+
+// WriteBird writes the IPv4 or IPv6 ROAs as route statements for a bird2
+// roa4 or roa6 table, e.g. to be included in a static protocol
+func (s *Set) WriteBird(w io.Writer, ipv6 bool) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# Generated from the DN42 registry at %s\n", s.Generated.UTC().Format(time.RFC3339))
+	for _, roa := range s.roas {
+		if roa.Prefix.Addr().Is6() != ipv6 {
+			continue
+		}
+		fmt.Fprintf(bw, "route %s max %d as %d;\n", roa.Prefix, roa.MaxLength, roa.Origin)
+	}
+	return bw.Flush()
+}
+
+// WriteJSON writes all ROAs in the JSON format of rpki-client, which RTR
+// servers such as stayrtr and gortr read
+func (s *Set) WriteJSON(w io.Writer) error {
+	type jsonROA struct {
+		ASN       uint32 `json:"asn"`
+		Prefix    string `json:"prefix"`
+		MaxLength int    `json:"maxLength"`
+		TA        string `json:"ta"`
+	}
+	out := struct {
+		Metadata struct {
+			BuildTime string `json:"buildtime"`
+			ROAs      int    `json:"roas"`
+		} `json:"metadata"`
+		ROAs []jsonROA `json:"roas"`
+	}{
+		ROAs: make([]jsonROA, len(s.roas)),
+	}
+	out.Metadata.BuildTime = s.Generated.UTC().Format(time.RFC3339)
+	out.Metadata.ROAs = len(s.roas)
+	for i, roa := range s.roas {
+		out.ROAs[i] = jsonROA{
+			ASN:       roa.Origin,
+			Prefix:    roa.Prefix.String(),
+			MaxLength: roa.MaxLength,
+			TA:        jsonTrustAnchor,
+		}
+	}
+	return json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,132 @@
+package roa
+
+import (
+	"cmp"
+	"net/netip"
+	"slices"
+	"time"
+
+	"github.com/iedon/dn42_map_go/registry"
+)
+
+// ROA is a route origin authorization derived from a route object
+type ROA struct {
+	Prefix    netip.Prefix
+	MaxLength int
+	Origin    uint32
+}
+
+// Validity is the origin validation state of an announcement
+type Validity int
+
+const (
+	ValidityNotFound      Validity = iota // No ROA covers the prefix
+	ValidityValid                         // A covering ROA matches origin and length
+	ValidityInvalidOrigin                 // No covering ROA is for the origin
+	ValidityInvalidLength                 // Covering ROAs for the origin only allow shorter prefixes
+)
+
+// String returns the name of a validation state
+func (v Validity) String() string {
+	switch v {
+	case ValidityValid:
+		return "valid"
+	case ValidityInvalidOrigin:
+		return "invalid-origin"
+	case ValidityInvalidLength:
+		return "invalid-length"
+	}
+	return "not-found"
+}
+
+// Set is the set of ROAs of a registry snapshot
+type Set struct {
+	Generated time.Time
+	roas      []ROA // Sorted by prefix, max length and origin
+	index     map[netip.Prefix][]ROA
+}
+
+// Build derives the ROAs of the route and route6 objects of a registry the
+// way DN42 generates them: the first filter rule covering a route decides
+// whether it is authorized, and its maximum length applies unless the route
+// sets a shorter max-length. Without filters every route is authorized up
+// to its max-length or its own length.
+func Build(reg *registry.Registry) *Set {
+	set := &Set{
+		Generated: time.Now(),
+		index:     make(map[netip.Prefix][]ROA),
+	}
+
+	filtered := len(reg.Filters()) > 0
+	for _, route := range reg.Routes() {
+		maxLength := route.MaxPrefixLength()
+		if filtered {
+			filter := reg.MatchFilter(route.Prefix)
+			if filter == nil || !filter.Permit {
+				continue
+			}
+			if route.Prefix.Bits() < filter.MinLength || route.Prefix.Bits() > filter.MaxLength {
+				continue
+			}
+			if route.MaxLength == 0 || route.MaxLength > filter.MaxLength {
+				maxLength = filter.MaxLength
+			}
+		}
+
+		for _, origin := range route.Origins {
+			set.roas = append(set.roas, ROA{Prefix: route.Prefix, MaxLength: maxLength, Origin: origin})
+		}
+	}
+
+	slices.SortFunc(set.roas, compareROAs)
+	set.roas = slices.Compact(set.roas)
+	for _, roa := range set.roas {
+		set.index[roa.Prefix] = append(set.index[roa.Prefix], roa)
+	}
+	return set
+}
+
+// compareROAs orders ROAs by address family, prefix, max length and origin
+func compareROAs(a, b ROA) int {
+	if c := a.Prefix.Addr().Compare(b.Prefix.Addr()); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(a.Prefix.Bits(), b.Prefix.Bits()); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(a.MaxLength, b.MaxLength); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Origin, b.Origin)
+}
+
+// ROAs returns all ROAs sorted by prefix
+func (s *Set) ROAs() []ROA {
+	return s.roas
+}
+
+// Validate classifies an announcement of prefix by origin and returns the
+// ROAs covering it
+func (s *Set) Validate(prefix netip.Prefix, origin uint32) (Validity, []ROA) {
+	prefix = prefix.Masked()
+	var covering []ROA
+	for bits := prefix.Bits(); bits >= 0; bits-- {
+		parent, _ := prefix.Addr().Prefix(bits)
+		covering = append(covering, s.index[parent]...)
+	}
+	if len(covering) == 0 {
+		return ValidityNotFound, nil
+	}
+
+	validity := ValidityInvalidOrigin
+	for _, roa := range covering {
+		if roa.Origin != origin {
+			continue
+		}
+		if prefix.Bits() <= roa.MaxLength {
+			return ValidityValid, covering
+		}
+		validity = ValidityInvalidLength
+	}
+	return validity, covering
+}
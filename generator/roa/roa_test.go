@@ -0,0 +1,159 @@
+package roa
+
+import (
+	"bytes"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/iedon/dn42_map_go/registry"
+)
+
+// testSet builds the ROAs of a temporary registry checkout with the given
+// files below its data directory
+func testSet(t *testing.T, files map[string]string) *Set {
+	t.Helper()
+	base := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(base, "data", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reg, err := registry.Load(base)
+	if err != nil {
+		t.Fatalf("registry.Load() error = %v", err)
+	}
+	return Build(reg)
+}
+
+// route returns a route or route6 object
+func route(class, prefix string, attributes ...string) string {
+	return class + ": " + prefix + "\n" + strings.Join(attributes, "\n") + "\n"
+}
+
+func TestBuild(t *testing.T) {
+	routes := map[string]string{
+		"route/172.20.0.0_24":     route("route", "172.20.0.0/24", "origin: AS4242420001", "origin: AS4242420002"),
+		"route/172.20.1.0_24":     route("route", "172.20.1.0/24", "origin: AS4242420003", "max-length: 26"),
+		"route/172.20.2.0_24":     route("route", "172.20.2.0/24", "origin: AS4242420004", "max-length: 32"),
+		"route/10.0.0.0_8":        route("route", "10.0.0.0/8", "origin: AS4242420005"),
+		"route6/fd42:4242:1::_48": route("route6", "fd42:4242:1::/48", "origin: AS4242420006"),
+	}
+
+	tests := []struct {
+		name    string
+		filters map[string]string
+		want    []string
+	}{
+		{
+			name: "without filters",
+			want: []string{
+				"10.0.0.0/8-8 AS4242420005",
+				"172.20.0.0/24-24 AS4242420001",
+				"172.20.0.0/24-24 AS4242420002",
+				"172.20.1.0/24-26 AS4242420003",
+				"172.20.2.0/24-32 AS4242420004",
+				"fd42:4242:1::/48-48 AS4242420006",
+			},
+		},
+		{
+			name: "with filters",
+			filters: map[string]string{
+				"filter.txt": "1 deny 172.20.0.0/24 24 32\n" +
+					"2 permit 172.20.0.0/14 21 29\n" +
+					"99 deny 0.0.0.0/0 0 32\n",
+				"filter6.txt": "1 permit fd00::/8 44 64\n",
+			},
+			want: []string{
+				"172.20.1.0/24-26 AS4242420003",
+				"172.20.2.0/24-29 AS4242420004",
+				"fd42:4242:1::/48-64 AS4242420006",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := make(map[string]string)
+			for name, content := range routes {
+				files[name] = content
+			}
+			for name, content := range tt.filters {
+				files[name] = content
+			}
+
+			var got []string
+			for _, r := range testSet(t, files).ROAs() {
+				got = append(got, fmt.Sprintf("%s-%d AS%d", r.Prefix, r.MaxLength, r.Origin))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ROAs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	set := testSet(t, map[string]string{
+		"route/172.20.0.0_24":     route("route", "172.20.0.0/24", "origin: AS4242420001", "max-length: 26"),
+		"route/172.20.0.0_16":     route("route", "172.20.0.0/16", "origin: AS4242420002"),
+		"route6/fd42:4242:1::_48": route("route6", "fd42:4242:1::/48", "origin: AS4242420003"),
+	})
+
+	tests := []struct {
+		prefix   string
+		origin   uint32
+		want     Validity
+		covering int
+	}{
+		{"172.20.0.0/24", 4242420001, ValidityValid, 2},
+		{"172.20.0.64/26", 4242420001, ValidityValid, 2},
+		{"172.20.0.0/27", 4242420001, ValidityInvalidLength, 2},
+		{"172.20.0.0/24", 4242420002, ValidityInvalidLength, 2},
+		{"172.20.0.0/24", 4242420009, ValidityInvalidOrigin, 2},
+		{"172.20.5.0/24", 4242420002, ValidityInvalidLength, 1},
+		{"172.20.0.0/16", 4242420002, ValidityValid, 1},
+		{"172.21.0.0/24", 4242420002, ValidityNotFound, 0},
+		{"fd42:4242:1:1::/64", 4242420003, ValidityInvalidLength, 1},
+		{"fd42:4242:1::/48", 4242420003, ValidityValid, 1},
+	}
+	for _, tt := range tests {
+		got, covering := set.Validate(netip.MustParsePrefix(tt.prefix), tt.origin)
+		if got != tt.want || len(covering) != tt.covering {
+			t.Errorf("Validate(%s, AS%d) = %s with %d ROAs, want %s with %d", tt.prefix, tt.origin, got, len(covering), tt.want, tt.covering)
+		}
+	}
+}
+
+func TestWriteBird(t *testing.T) {
+	set := testSet(t, map[string]string{
+		"route/172.20.0.0_24":     route("route", "172.20.0.0/24", "origin: AS4242420001", "max-length: 26"),
+		"route6/fd42:4242:1::_48": route("route6", "fd42:4242:1::/48", "origin: AS4242420003"),
+	})
+
+	tests := []struct {
+		ipv6 bool
+		want string
+	}{
+		{false, "route 172.20.0.0/24 max 26 as 4242420001;\n"},
+		{true, "route fd42:4242:1::/48 max 48 as 4242420003;\n"},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		if err := set.WriteBird(&b, tt.ipv6); err != nil {
+			t.Fatalf("WriteBird() error = %v", err)
+		}
+		header, body, _ := strings.Cut(b.String(), "\n")
+		if !strings.HasPrefix(header, "# Generated from the DN42 registry at ") || body != tt.want {
+			t.Errorf("WriteBird(ipv6 %v) = %q, want %q", tt.ipv6, b.String(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package rtr
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/iedon/dn42_map_go/roa"
+)
+
+// clientTimeout bounds the wait for a complete response
+const clientTimeout = 30 * time.Second
+
+// Client is a minimal RTR router for checking a cache. It keeps the ROAs
+// received and fails on any response a router would reject.
+type Client struct {
+	conn    net.Conn
+	version uint8
+
+	SessionID uint16
+	Serial    uint32
+	Timing    Timing // Zero for version 0 caches
+	roas      map[roa.ROA]struct{}
+	synced    bool // Whether a Reset Query has completed, so Serial and SessionID are valid
+}
+
+// Update describes the changes of a response
+type Update struct {
+	Announced int
+	Withdrawn int
+	Reset     bool // The cache answered the Serial Query with a Cache Reset
+}
+
+// Dial connects to a cache, speaking version 1 of the protocol
+func Dial(addr string) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, clientTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, version: version1, roas: make(map[roa.ROA]struct{})}, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// ROAs returns the ROAs received so far, in no particular order
+func (c *Client) ROAs() []roa.ROA {
+	roas := make([]roa.ROA, 0, len(c.roas))
+	for r := range c.roas {
+		roas = append(roas, r)
+	}
+	return roas
+}
+
+// Reset sends a Reset Query and replaces the ROAs with the full set of the cache
+func (c *Client) Reset() (Update, error) {
+	if err := c.send(appendHeader(nil, c.version, pduResetQuery, 0, headerLength)); err != nil {
+		return Update{}, err
+	}
+	c.roas = make(map[roa.ROA]struct{})
+	update, err := c.receive(false)
+	if err == nil {
+		c.synced = true
+	}
+	return update, err
+}
+
+// Refresh sends a Serial Query for the current serial and applies the changes.
+// If the cache answers with a Cache Reset it falls back to Reset.
+func (c *Client) Refresh() (Update, error) {
+	if !c.synced {
+		return Update{}, fmt.Errorf("no Reset Query completed yet")
+	}
+	if err := c.send(appendSerialPDU(nil, c.version, pduSerialQuery, c.SessionID, c.Serial)); err != nil {
+		return Update{}, err
+	}
+	update, err := c.receive(true)
+	if err != nil || !update.Reset {
+		return update, err
+	}
+	update, err = c.Reset()
+	update.Reset = true
+	return update, err
+}
+
+// send writes PDUs to the cache
+func (c *Client) send(b []byte) error {
+	c.conn.SetWriteDeadline(time.Now().Add(clientTimeout))
+	_, err := c.conn.Write(b)
+	return err
+}
+
+// receive reads a response up to its End of Data. Serial Notify PDUs sent
+// in between are skipped.
+func (c *Client) receive(serialQuery bool) (Update, error) {
+	var update Update
+	c.conn.SetReadDeadline(time.Now().Add(clientTimeout))
+	started := false
+	for {
+		p, err := readPDU(c.conn)
+		if err != nil {
+			return update, err
+		}
+		if p.version != c.version {
+			return update, c.fail(errUnexpectedVersion, p, fmt.Sprintf("unexpected version %d", p.version))
+		}
+
+		switch p.typ {
+		case pduSerialNotify:
+			continue
+		case pduErrorReport:
+			code, text := parseErrorReport(p)
+			return update, fmt.Errorf("cache reported error %d: %s", code, text)
+		case pduCacheReset:
+			if !serialQuery || started {
+				return update, c.fail(errInvalidRequest, p, "unexpected Cache Reset")
+			}
+			update.Reset = true
+			return update, nil
+		case pduCacheResponse:
+			if started {
+				return update, c.fail(errInvalidRequest, p, "unexpected Cache Response")
+			}
+			if serialQuery && p.field != c.SessionID {
+				return update, c.fail(errCorruptData, p, "session ID changed")
+			}
+			started = true
+			c.SessionID = p.field
+		case pduIPv4Prefix, pduIPv6Prefix:
+			if !started {
+				return update, c.fail(errInvalidRequest, p, "prefix outside of a Cache Response")
+			}
+			announce, r, err := parsePrefixPDU(p)
+			if err != nil {
+				return update, c.fail(errCorruptData, p, err.Error())
+			}
+			_, known := c.roas[r]
+			switch {
+			case announce && known:
+				return update, c.fail(errDuplicateAnnouncement, p, "duplicate announcement")
+			case announce:
+				c.roas[r] = struct{}{}
+				update.Announced++
+			case !known:
+				return update, c.fail(errWithdrawalOfUnknownRecord, p, "withdrawal of unknown record")
+			default:
+				delete(c.roas, r)
+				update.Withdrawn++
+			}
+		case pduEndOfData:
+			if !started || p.field != c.SessionID {
+				return update, c.fail(errInvalidRequest, p, "unexpected End of Data")
+			}
+			if err := c.parseEndOfData(p); err != nil {
+				return update, c.fail(errCorruptData, p, err.Error())
+			}
+			return update, nil
+		default:
+			return update, c.fail(errUnsupportedPDUType, p, "unsupported PDU type")
+		}
+	}
+}
+
+// parseEndOfData takes the serial and timing of an End of Data PDU
+func (c *Client) parseEndOfData(p *pdu) error {
+	if c.version == version0 {
+		if len(p.body) != 4 {
+			return fmt.Errorf("invalid End of Data length")
+		}
+		c.Serial = binary.BigEndian.Uint32(p.body)
+		return nil
+	}
+	if len(p.body) != 16 {
+		return fmt.Errorf("invalid End of Data length")
+	}
+	c.Serial = binary.BigEndian.Uint32(p.body)
+	c.Timing = Timing{
+		Refresh: binary.BigEndian.Uint32(p.body[4:]),
+		Retry:   binary.BigEndian.Uint32(p.body[8:]),
+		Expire:  binary.BigEndian.Uint32(p.body[12:]),
+	}
+	return nil
+}
+
+// parsePrefixPDU returns whether a Prefix PDU announces and its ROA
+func parsePrefixPDU(p *pdu) (bool, roa.ROA, error) {
+	addrLength := 4
+	if p.typ == pduIPv6Prefix {
+		addrLength = 16
+	}
+	if len(p.body) != 4+addrLength+4 {
+		return false, roa.ROA{}, fmt.Errorf("invalid Prefix PDU length")
+	}
+
+	addr, _ := netip.AddrFromSlice(p.body[4 : 4+addrLength])
+	prefix, err := addr.Prefix(int(p.body[1]))
+	if err != nil || prefix.Addr() != addr {
+		return false, roa.ROA{}, fmt.Errorf("invalid prefix %s/%d", addr, p.body[1])
+	}
+	maxLength := int(p.body[2])
+	if maxLength < prefix.Bits() || maxLength > addr.BitLen() {
+		return false, roa.ROA{}, fmt.Errorf("invalid max length %d for %s", maxLength, prefix)
+	}
+	return p.body[0]&flagAnnounce != 0, roa.ROA{
+		Prefix:    prefix,
+		MaxLength: maxLength,
+		Origin:    binary.BigEndian.Uint32(p.body[4+addrLength:]),
+	}, nil
+}
+
+// fail reports a protocol error to the cache like a router would and returns it
+func (c *Client) fail(code uint16, p *pdu, text string) error {
+	c.send(appendErrorReport(nil, c.version, code, p.raw, text))
+	return fmt.Errorf("%s (error %d)", text, code)
+}
@@ -0,0 +1,161 @@
+package rtr
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/iedon/dn42_map_go/roa"
+)
+
+// Protocol versions, version 1 is RFC 8210 and version 0 is RFC 6810
+const (
+	version0   = 0
+	version1   = 1
+	maxVersion = version1
+)
+
+// PDU types
+const (
+	pduSerialNotify  = 0
+	pduSerialQuery   = 1
+	pduResetQuery    = 2
+	pduCacheResponse = 3
+	pduIPv4Prefix    = 4
+	pduIPv6Prefix    = 6
+	pduEndOfData     = 7
+	pduCacheReset    = 8
+	pduRouterKey     = 9
+	pduErrorReport   = 10
+)
+
+// Error Report codes
+const (
+	errCorruptData               = 0
+	errInternalError             = 1
+	errNoDataAvailable           = 2
+	errInvalidRequest            = 3
+	errUnsupportedVersion        = 4
+	errUnsupportedPDUType        = 5
+	errWithdrawalOfUnknownRecord = 6
+	errDuplicateAnnouncement     = 7
+	errUnexpectedVersion         = 8
+)
+
+const (
+	headerLength = 8
+	// maxPDULength bounds the PDUs read, the largest regular PDU is an
+	// Error Report with an encapsulated PDU and a diagnostic text
+	maxPDULength = 64 * 1024
+)
+
+// Prefix PDU flags
+const flagAnnounce = 1
+
+// errPDULength is returned by readPDU for a length outside the valid range
+var errPDULength = errors.New("invalid PDU length")
+
+// pdu is a received PDU. field is the session ID or error code of the
+// header, raw the complete PDU for encapsulation in an Error Report.
+type pdu struct {
+	version uint8
+	typ     uint8
+	field   uint16
+	body    []byte
+	raw     []byte
+}
+
+// readPDU reads the next PDU from r
+func readPDU(r io.Reader) (*pdu, error) {
+	header := make([]byte, headerLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[4:8])
+	if length < headerLength || length > maxPDULength {
+		return nil, errPDULength
+	}
+
+	raw := make([]byte, length)
+	copy(raw, header)
+	if _, err := io.ReadFull(r, raw[headerLength:]); err != nil {
+		return nil, err
+	}
+	return &pdu{
+		version: header[0],
+		typ:     header[1],
+		field:   binary.BigEndian.Uint16(header[2:4]),
+		body:    raw[headerLength:],
+		raw:     raw,
+	}, nil
+}
+
+// appendHeader appends a PDU header
+func appendHeader(b []byte, version, typ uint8, field uint16, length uint32) []byte {
+	b = append(b, version, typ)
+	b = binary.BigEndian.AppendUint16(b, field)
+	return binary.BigEndian.AppendUint32(b, length)
+}
+
+// appendSerialPDU appends a Serial Notify or Serial Query PDU
+func appendSerialPDU(b []byte, version, typ uint8, sessionID uint16, serial uint32) []byte {
+	b = appendHeader(b, version, typ, sessionID, 12)
+	return binary.BigEndian.AppendUint32(b, serial)
+}
+
+// appendPrefixPDU appends an IPv4 or IPv6 Prefix PDU announcing or withdrawing a ROA
+func appendPrefixPDU(b []byte, version uint8, announce bool, r roa.ROA) []byte {
+	var flags uint8
+	if announce {
+		flags = flagAnnounce
+	}
+	addr := r.Prefix.Addr()
+	if addr.Is4() {
+		b = appendHeader(b, version, pduIPv4Prefix, 0, 20)
+	} else {
+		b = appendHeader(b, version, pduIPv6Prefix, 0, 32)
+	}
+	b = append(b, flags, uint8(r.Prefix.Bits()), uint8(r.MaxLength), 0)
+	b = append(b, addr.AsSlice()...)
+	return binary.BigEndian.AppendUint32(b, r.Origin)
+}
+
+// appendEndOfData appends an End of Data PDU, version 0 carries no timing
+func appendEndOfData(b []byte, version uint8, sessionID uint16, serial uint32, timing Timing) []byte {
+	if version == version0 {
+		return appendSerialPDU(b, version, pduEndOfData, sessionID, serial)
+	}
+	b = appendHeader(b, version, pduEndOfData, sessionID, 24)
+	b = binary.BigEndian.AppendUint32(b, serial)
+	b = binary.BigEndian.AppendUint32(b, timing.Refresh)
+	b = binary.BigEndian.AppendUint32(b, timing.Retry)
+	return binary.BigEndian.AppendUint32(b, timing.Expire)
+}
+
+// appendErrorReport appends an Error Report PDU with the erroneous PDU and a
+// diagnostic text
+func appendErrorReport(b []byte, version uint8, code uint16, encapsulated []byte, text string) []byte {
+	length := headerLength + 4 + len(encapsulated) + 4 + len(text)
+	b = appendHeader(b, version, pduErrorReport, code, uint32(length))
+	b = binary.BigEndian.AppendUint32(b, uint32(len(encapsulated)))
+	b = append(b, encapsulated...)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(text)))
+	return append(b, text...)
+}
+
+// parseErrorReport returns the error code and diagnostic text of an Error Report
+func parseErrorReport(p *pdu) (uint16, string) {
+	body := p.body
+	if len(body) < 4 {
+		return p.field, ""
+	}
+	skip := 4 + int(binary.BigEndian.Uint32(body))
+	if skip+4 > len(body) {
+		return p.field, ""
+	}
+	textLength := int(binary.BigEndian.Uint32(body[skip:]))
+	if skip+4+textLength > len(body) {
+		return p.field, ""
+	}
+	return p.field, string(body[skip+4 : skip+4+textLength])
+}
@@ -0,0 +1,203 @@
+package rtr
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/iedon/dn42_map_go/roa"
+)
+
+// mustDecodeHex decodes a hex fixture, spaces are ignored
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestEncodePDU(t *testing.T) {
+	ipv4 := roa.ROA{Prefix: netip.MustParsePrefix("172.20.0.0/14"), MaxLength: 24, Origin: 4242420001}
+	ipv6 := roa.ROA{Prefix: netip.MustParsePrefix("fd42:4242:1::/48"), MaxLength: 64, Origin: 4242420002}
+	timing := Timing{Refresh: 3600, Retry: 600, Expire: 7200}
+
+	tests := []struct {
+		name string
+		got  []byte
+		want string
+	}{
+		{
+			name: "Reset Query",
+			got:  appendHeader(nil, version1, pduResetQuery, 0, headerLength),
+			want: "01 02 0000 00000008",
+		},
+		{
+			name: "Serial Notify",
+			got:  appendSerialPDU(nil, version1, pduSerialNotify, 0x1234, 7),
+			want: "01 00 1234 0000000c 00000007",
+		},
+		{
+			name: "Serial Query version 0",
+			got:  appendSerialPDU(nil, version0, pduSerialQuery, 0x1234, 7),
+			want: "00 01 1234 0000000c 00000007",
+		},
+		{
+			name: "IPv4 Prefix announcement",
+			got:  appendPrefixPDU(nil, version1, true, ipv4),
+			want: "01 04 0000 00000014 01 0e 18 00 ac140000 fcde3121",
+		},
+		{
+			name: "IPv6 Prefix withdrawal",
+			got:  appendPrefixPDU(nil, version1, false, ipv6),
+			want: "01 06 0000 00000020 00 30 40 00 fd424242000100000000000000000000 fcde3122",
+		},
+		{
+			name: "End of Data version 1",
+			got:  appendEndOfData(nil, version1, 0x1234, 7, timing),
+			want: "01 07 1234 00000018 00000007 00000e10 00000258 00001c20",
+		},
+		{
+			name: "End of Data version 0",
+			got:  appendEndOfData(nil, version0, 0x1234, 7, timing),
+			want: "00 07 1234 0000000c 00000007",
+		},
+		{
+			name: "Cache Reset",
+			got:  appendHeader(nil, version1, pduCacheReset, 0, headerLength),
+			want: "01 08 0000 00000008",
+		},
+		{
+			name: "Error Report",
+			got:  appendErrorReport(nil, version1, errInvalidRequest, mustDecodeHex("01 02 0000 00000008"), "bad"),
+			want: "01 0a 0003 0000001b 00000008 01020000 00000008 00000003 626164",
+		},
+		{
+			name: "Error Report without PDU and text",
+			got:  appendErrorReport(nil, version0, errNoDataAvailable, nil, ""),
+			want: "00 0a 0002 00000010 00000000 00000000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if want := mustDecodeHex(tt.want); !bytes.Equal(tt.got, want) {
+				t.Errorf("encoded % x, want % x", tt.got, want)
+			}
+		})
+	}
+}
+
+func TestReadPDU(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		typ     uint8
+		field   uint16
+		body    string
+		wantErr error
+	}{
+		{name: "Reset Query", data: "01 02 0000 00000008", typ: pduResetQuery},
+		{name: "Serial Query", data: "01 01 1234 0000000c 00000007", typ: pduSerialQuery, field: 0x1234, body: "00000007"},
+		{name: "trailing data", data: "01 02 0000 00000008 01", typ: pduResetQuery},
+		{name: "length below header", data: "01 02 0000 00000004", wantErr: errPDULength},
+		{name: "length above maximum", data: "01 02 0000 00010001", wantErr: errPDULength},
+		{name: "truncated body", data: "01 01 1234 0000000c 0000", wantErr: io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := readPDU(bytes.NewReader(mustDecodeHex(tt.data)))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("readPDU() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readPDU() error = %v", err)
+			}
+			if p.version != version1 || p.typ != tt.typ || p.field != tt.field || !bytes.Equal(p.body, mustDecodeHex(tt.body)) {
+				t.Errorf("readPDU() = version %d type %d field %d body % x", p.version, p.typ, p.field, p.body)
+			}
+			if len(p.raw) != headerLength+len(p.body) {
+				t.Errorf("raw PDU has %d bytes, want %d", len(p.raw), headerLength+len(p.body))
+			}
+		})
+	}
+}
+
+func TestParseErrorReport(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		code uint16
+		text string
+	}{
+		{"with PDU and text", appendErrorReport(nil, version1, errCorruptData, mustDecodeHex("01 02 0000 00000008"), "corrupt"), errCorruptData, "corrupt"},
+		{"without PDU", appendErrorReport(nil, version1, errNoDataAvailable, nil, "no data"), errNoDataAvailable, "no data"},
+		{"truncated text", mustDecodeHex("01 0a 0001 00000012 00000000 000000ff 6162"), errInternalError, ""},
+		{"PDU length beyond body", mustDecodeHex("01 0a 0001 0000000c 000000ff"), errInternalError, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := readPDU(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("readPDU() error = %v", err)
+			}
+			if code, text := parseErrorReport(p); code != tt.code || text != tt.text {
+				t.Errorf("parseErrorReport() = %d %q, want %d %q", code, text, tt.code, tt.text)
+			}
+		})
+	}
+}
+
+func TestParsePrefixPDU(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		announce bool
+		want     roa.ROA
+		wantErr  bool
+	}{
+		{
+			name:     "IPv4 announcement",
+			data:     "01 04 0000 00000014 01 0e 18 00 ac140000 fcde3121",
+			announce: true,
+			want:     roa.ROA{Prefix: netip.MustParsePrefix("172.20.0.0/14"), MaxLength: 24, Origin: 4242420001},
+		},
+		{
+			name: "IPv6 withdrawal",
+			data: "01 06 0000 00000020 00 30 40 00 fd424242000100000000000000000000 fcde3122",
+			want: roa.ROA{Prefix: netip.MustParsePrefix("fd42:4242:1::/48"), MaxLength: 64, Origin: 4242420002},
+		},
+		{name: "host bits set", data: "01 04 0000 00000014 01 0e 18 00 ac140001 fcde3121", wantErr: true},
+		{name: "max length below prefix length", data: "01 04 0000 00000014 01 18 10 00 ac140000 fcde3121", wantErr: true},
+		{name: "max length above address length", data: "01 04 0000 00000014 01 18 21 00 ac140000 fcde3121", wantErr: true},
+		{name: "IPv6 length for IPv4", data: "01 04 0000 00000020 01 0e 18 00 fd424242000100000000000000000000 fcde3121", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := readPDU(bytes.NewReader(mustDecodeHex(tt.data)))
+			if err != nil {
+				t.Fatalf("readPDU() error = %v", err)
+			}
+			announce, r, err := parsePrefixPDU(p)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parsePrefixPDU() = %v, want an error", r)
+				}
+				return
+			}
+			if err != nil || announce != tt.announce || r != tt.want {
+				t.Errorf("parsePrefixPDU() = %v %v %v, want %v %v", announce, r, err, tt.announce, tt.want)
+			}
+		})
+	}
+}
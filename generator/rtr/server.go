@@ -0,0 +1,163 @@
+package rtr
+
+import (
+	"log"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"sync"
+
+	"github.com/iedon/dn42_map_go/roa"
+)
+
+// historySize is the number of previous serials kept for incremental
+// Serial Queries, older serials get a Cache Reset
+const historySize = 32
+
+// Timing holds the intervals in seconds sent to routers in End of Data
+type Timing struct {
+	Refresh uint32
+	Retry   uint32
+	Expire  uint32
+}
+
+// DefaultTiming are the intervals recommended by RFC 8210
+var DefaultTiming = Timing{Refresh: 3600, Retry: 600, Expire: 7200}
+
+// snapshot is the ROA set of one serial
+type snapshot struct {
+	serial uint32
+	roas   []roa.ROA // Sorted and without duplicates, as returned by roa.Set
+}
+
+// Server is an RPKI-to-Router cache serving the ROAs of the registry to
+// routers over RFC 8210, falling back to RFC 6810 for older routers
+type Server struct {
+	timing    Timing
+	sessionID uint16
+
+	mu       sync.RWMutex
+	history  []snapshot // Oldest first, the last one is served, empty until the first Update
+	sessions map[*session]struct{}
+}
+
+// NewServer creates an RTR server with a random session ID. Zero intervals
+// of timing use the defaults.
+func NewServer(timing Timing) *Server {
+	if timing.Refresh == 0 {
+		timing.Refresh = DefaultTiming.Refresh
+	}
+	if timing.Retry == 0 {
+		timing.Retry = DefaultTiming.Retry
+	}
+	if timing.Expire == 0 {
+		timing.Expire = DefaultTiming.Expire
+	}
+	return &Server{
+		timing:    timing,
+		sessionID: uint16(rand.Uint32()),
+		sessions:  make(map[*session]struct{}),
+	}
+}
+
+// Update serves a new ROA set. If it differs from the current one the
+// serial is incremented and connected routers get a Serial Notify.
+func (s *Server) Update(set *roa.Set) {
+	roas := set.ROAs()
+
+	s.mu.Lock()
+	var serial uint32
+	if len(s.history) > 0 {
+		current := s.history[len(s.history)-1]
+		if slices.Equal(current.roas, roas) {
+			s.mu.Unlock()
+			return
+		}
+		serial = current.serial + 1
+	}
+	s.history = append(s.history, snapshot{serial: serial, roas: roas})
+	if len(s.history) > historySize {
+		s.history = slices.Delete(s.history, 0, len(s.history)-historySize)
+	}
+	sessions := make([]*session, 0, len(s.sessions))
+	for sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	s.mu.Unlock()
+
+	log.Printf("RTR serial %d with %d ROAs, notifying %d routers\n", serial, len(roas), len(sessions))
+	for _, sess := range sessions {
+		sess.notify(serial)
+	}
+}
+
+// ListenAndServe accepts router connections on addr
+func (s *Server) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	log.Printf("Starting RTR server on %s\n", addr)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		sess := &session{server: s, conn: conn}
+		s.mu.Lock()
+		s.sessions[sess] = struct{}{}
+		s.mu.Unlock()
+
+		go func() {
+			sess.run()
+			s.mu.Lock()
+			delete(s.sessions, sess)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// current returns the served snapshot, false if there is no data yet
+func (s *Server) current() (snapshot, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.history) == 0 {
+		return snapshot{}, false
+	}
+	return s.history[len(s.history)-1], true
+}
+
+// delta returns the changes from an earlier serial to the served one, false
+// if the serial is no longer or was never known
+func (s *Server) delta(serial uint32) (announced, withdrawn []roa.ROA, current uint32, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := slices.IndexFunc(s.history, func(snap snapshot) bool {
+		return snap.serial == serial
+	})
+	if i < 0 {
+		return nil, nil, 0, false
+	}
+	from, to := s.history[i], s.history[len(s.history)-1]
+
+	previous := make(map[roa.ROA]struct{}, len(from.roas))
+	for _, r := range from.roas {
+		previous[r] = struct{}{}
+	}
+	for _, r := range to.roas {
+		if _, found := previous[r]; found {
+			delete(previous, r)
+		} else {
+			announced = append(announced, r)
+		}
+	}
+	for _, r := range from.roas {
+		if _, found := previous[r]; found {
+			withdrawn = append(withdrawn, r)
+		}
+	}
+	return announced, withdrawn, to.serial, true
+}
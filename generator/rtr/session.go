@@ -0,0 +1,179 @@
+package rtr
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// writeTimeout bounds writes to a router, so a stalled router cannot block
+// the Serial Notify of the others
+const writeTimeout = 30 * time.Second
+
+// session is the connection of one router
+type session struct {
+	server *Server
+	conn   net.Conn
+
+	mu         sync.Mutex // Serializes writes, responses and Serial Notify may interleave
+	negotiated bool
+	version    uint8
+}
+
+// run serves the queries of the router until the connection is closed or a
+// fatal error is reported
+func (sess *session) run() {
+	defer sess.conn.Close()
+	remote := sess.conn.RemoteAddr()
+	log.Printf("RTR router %s connected\n", remote)
+
+	for {
+		p, err := readPDU(sess.conn)
+		if err != nil {
+			switch {
+			case errors.Is(err, errPDULength):
+				sess.reportError(errCorruptData, nil, "invalid PDU length")
+				log.Printf("RTR router %s sent an invalid PDU length\n", remote)
+			case errors.Is(err, io.EOF):
+				log.Printf("RTR router %s disconnected\n", remote)
+			default:
+				log.Printf("RTR router %s: %v\n", remote, err)
+			}
+			return
+		}
+		if !sess.handle(p) {
+			return
+		}
+	}
+}
+
+// handle answers a PDU and returns false if the session has to be closed
+func (sess *session) handle(p *pdu) bool {
+	remote := sess.conn.RemoteAddr()
+
+	sess.mu.Lock()
+	if !sess.negotiated {
+		if p.version > maxVersion {
+			sess.mu.Unlock()
+			// Reported with the highest supported version so the router can downgrade
+			sess.reportErrorVersion(maxVersion, errUnsupportedVersion, p.raw, "unsupported protocol version")
+			log.Printf("RTR router %s requested unsupported version %d\n", remote, p.version)
+			return false
+		}
+		sess.negotiated = true
+		sess.version = p.version
+	}
+	version := sess.version
+	sess.mu.Unlock()
+
+	if p.version != version {
+		sess.reportError(errUnexpectedVersion, p.raw, "protocol version changed during the session")
+		log.Printf("RTR router %s changed version from %d to %d\n", remote, version, p.version)
+		return false
+	}
+
+	switch p.typ {
+	case pduResetQuery:
+		if len(p.raw) != headerLength {
+			sess.reportError(errCorruptData, p.raw, "invalid Reset Query length")
+			return false
+		}
+		sess.sendFull()
+	case pduSerialQuery:
+		if len(p.raw) != headerLength+4 {
+			sess.reportError(errCorruptData, p.raw, "invalid Serial Query length")
+			return false
+		}
+		sess.sendDelta(p.field, binary.BigEndian.Uint32(p.body))
+	case pduErrorReport:
+		code, text := parseErrorReport(p)
+		log.Printf("RTR router %s reported error %d: %s\n", remote, code, text)
+		return false
+	case pduSerialNotify, pduCacheResponse, pduIPv4Prefix, pduIPv6Prefix, pduEndOfData, pduCacheReset, pduRouterKey:
+		sess.reportError(errInvalidRequest, p.raw, "PDU type is only sent by caches")
+		log.Printf("RTR router %s sent cache PDU type %d\n", remote, p.typ)
+		return false
+	default:
+		sess.reportError(errUnsupportedPDUType, p.raw, "unsupported PDU type")
+		log.Printf("RTR router %s sent unsupported PDU type %d\n", remote, p.typ)
+		return false
+	}
+	return true
+}
+
+// sendFull answers a Reset Query with every ROA of the served snapshot
+func (sess *session) sendFull() {
+	snap, ok := sess.server.current()
+	if !ok {
+		sess.reportError(errNoDataAvailable, nil, "no data available yet")
+		return
+	}
+
+	b := appendHeader(nil, sess.version, pduCacheResponse, sess.server.sessionID, headerLength)
+	for _, r := range snap.roas {
+		b = appendPrefixPDU(b, sess.version, true, r)
+	}
+	b = appendEndOfData(b, sess.version, sess.server.sessionID, snap.serial, sess.server.timing)
+	sess.write(b)
+}
+
+// sendDelta answers a Serial Query with the changes since serial, or a Cache
+// Reset if they cannot be computed
+func (sess *session) sendDelta(sessionID uint16, serial uint32) {
+	if _, ok := sess.server.current(); !ok {
+		sess.reportError(errNoDataAvailable, nil, "no data available yet")
+		return
+	}
+	announced, withdrawn, current, ok := sess.server.delta(serial)
+	if sessionID != sess.server.sessionID || !ok {
+		sess.write(appendHeader(nil, sess.version, pduCacheReset, 0, headerLength))
+		return
+	}
+
+	b := appendHeader(nil, sess.version, pduCacheResponse, sess.server.sessionID, headerLength)
+	for _, r := range withdrawn {
+		b = appendPrefixPDU(b, sess.version, false, r)
+	}
+	for _, r := range announced {
+		b = appendPrefixPDU(b, sess.version, true, r)
+	}
+	b = appendEndOfData(b, sess.version, sess.server.sessionID, current, sess.server.timing)
+	sess.write(b)
+}
+
+// notify sends a Serial Notify, routers that have not queried yet are skipped
+func (sess *session) notify(serial uint32) {
+	sess.mu.Lock()
+	negotiated, version := sess.negotiated, sess.version
+	sess.mu.Unlock()
+	if negotiated {
+		sess.write(appendSerialPDU(nil, version, pduSerialNotify, sess.server.sessionID, serial))
+	}
+}
+
+// reportError sends an Error Report in the negotiated version
+func (sess *session) reportError(code uint16, encapsulated []byte, text string) {
+	sess.mu.Lock()
+	version := sess.version
+	sess.mu.Unlock()
+	sess.reportErrorVersion(version, code, encapsulated, text)
+}
+
+func (sess *session) reportErrorVersion(version uint8, code uint16, encapsulated []byte, text string) {
+	sess.write(appendErrorReport(nil, version, code, encapsulated, text))
+}
+
+// write sends PDUs to the router, closing the connection on failure
+func (sess *session) write(b []byte) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := sess.conn.Write(b); err != nil {
+		log.Printf("RTR router %s: %v\n", sess.conn.RemoteAddr(), err)
+		sess.conn.Close()
+	}
+}
@@ -0,0 +1,174 @@
+package rtr
+
+import (
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/iedon/dn42_map_go/roa"
+)
+
+// testSession connects a client speaking version to a session of s
+func testSession(t *testing.T, s *Server, version uint8) *Client {
+	t.Helper()
+	routerConn, cacheConn := net.Pipe()
+	sess := &session{server: s, conn: cacheConn}
+	go sess.run()
+	t.Cleanup(func() { routerConn.Close() })
+	return &Client{conn: routerConn, version: version, roas: make(map[roa.ROA]struct{})}
+}
+
+// sortedROAs returns the ROAs of a client in the order of a roa.Set
+func sortedROAs(c *Client) []roa.ROA {
+	roas := c.ROAs()
+	slices.SortFunc(roas, func(a, b roa.ROA) int {
+		return strings.Compare(a.Prefix.String(), b.Prefix.String())
+	})
+	return roas
+}
+
+func TestSession(t *testing.T) {
+	a := roa.ROA{Prefix: netip.MustParsePrefix("172.20.0.0/24"), MaxLength: 28, Origin: 4242420001}
+	b := roa.ROA{Prefix: netip.MustParsePrefix("172.20.1.0/24"), MaxLength: 24, Origin: 4242420002}
+	c := roa.ROA{Prefix: netip.MustParsePrefix("fd42:4242:1::/48"), MaxLength: 64, Origin: 4242420003}
+
+	s := NewServer(Timing{Refresh: 900})
+	s.history = []snapshot{
+		{serial: 0, roas: []roa.ROA{a, b}},
+		{serial: 1, roas: []roa.ROA{a, c}},
+	}
+
+	tests := []struct {
+		name    string
+		version uint8
+		serial  uint32 // Serial of the Serial Query after the Reset Query
+		update  Update
+		timing  Timing
+	}{
+		{
+			name:    "current serial",
+			version: version1,
+			serial:  1,
+			timing:  Timing{Refresh: 900, Retry: 600, Expire: 7200},
+		},
+		{
+			name:    "previous serial",
+			version: version1,
+			serial:  0,
+			update:  Update{Announced: 1, Withdrawn: 1},
+			timing:  Timing{Refresh: 900, Retry: 600, Expire: 7200},
+		},
+		{
+			name:    "unknown serial",
+			version: version1,
+			serial:  5,
+			update:  Update{Announced: 2, Reset: true},
+			timing:  Timing{Refresh: 900, Retry: 600, Expire: 7200},
+		},
+		{
+			name:    "version 0",
+			version: version0,
+			serial:  0,
+			update:  Update{Announced: 1, Withdrawn: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := testSession(t, s, tt.version)
+			update, err := client.Reset()
+			if err != nil {
+				t.Fatalf("Reset() error = %v", err)
+			}
+			if update.Announced != 2 || client.Serial != 1 || client.SessionID != s.sessionID || client.Timing != tt.timing {
+				t.Errorf("Reset() = %+v serial %d session %d timing %+v", update, client.Serial, client.SessionID, client.Timing)
+			}
+
+			// Roll the client back to the ROAs of the serial it queries
+			if tt.serial == 0 {
+				client.roas = map[roa.ROA]struct{}{a: {}, b: {}}
+			}
+			client.Serial = tt.serial
+			update, err = client.Refresh()
+			if err != nil {
+				t.Fatalf("Refresh() error = %v", err)
+			}
+			if update != tt.update {
+				t.Errorf("Refresh() = %+v, want %+v", update, tt.update)
+			}
+			if got := sortedROAs(client); !slices.Equal(got, []roa.ROA{a, c}) {
+				t.Errorf("ROAs = %v, want %v", got, []roa.ROA{a, c})
+			}
+			if client.Serial != 1 {
+				t.Errorf("serial = %d, want 1", client.Serial)
+			}
+		})
+	}
+}
+
+func TestSessionErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		server  *Server
+		version uint8
+		wantErr string
+	}{
+		{
+			name:    "no data",
+			server:  NewServer(Timing{}),
+			version: version1,
+			wantErr: "cache reported error 2: no data available yet",
+		},
+		{
+			name:    "unsupported version",
+			server:  NewServer(Timing{}),
+			version: 2,
+			wantErr: "unexpected version 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := testSession(t, tt.server, tt.version)
+			if _, err := client.Reset(); err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("Reset() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServerDelta(t *testing.T) {
+	a := roa.ROA{Prefix: netip.MustParsePrefix("172.20.0.0/24"), MaxLength: 28, Origin: 4242420001}
+	b := roa.ROA{Prefix: netip.MustParsePrefix("172.20.1.0/24"), MaxLength: 24, Origin: 4242420002}
+	c := roa.ROA{Prefix: netip.MustParsePrefix("172.20.2.0/24"), MaxLength: 24, Origin: 4242420003}
+
+	s := NewServer(Timing{})
+	s.history = []snapshot{
+		{serial: 4, roas: []roa.ROA{a}},
+		{serial: 5, roas: []roa.ROA{a, b}},
+		{serial: 6, roas: []roa.ROA{b, c}},
+	}
+
+	tests := []struct {
+		serial    uint32
+		announced []roa.ROA
+		withdrawn []roa.ROA
+		ok        bool
+	}{
+		{serial: 4, announced: []roa.ROA{b, c}, withdrawn: []roa.ROA{a}, ok: true},
+		{serial: 5, announced: []roa.ROA{c}, withdrawn: []roa.ROA{a}, ok: true},
+		{serial: 6, ok: true},
+		{serial: 3},
+	}
+	for _, tt := range tests {
+		announced, withdrawn, current, ok := s.delta(tt.serial)
+		if ok != tt.ok || !slices.Equal(announced, tt.announced) || !slices.Equal(withdrawn, tt.withdrawn) {
+			t.Errorf("delta(%d) = %v %v %v, want %v %v %v", tt.serial, announced, withdrawn, ok, tt.announced, tt.withdrawn, tt.ok)
+		}
+		if ok && current != 6 {
+			t.Errorf("delta(%d) current serial = %d, want 6", tt.serial, current)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/iedon/dn42_map_go/rtr"
+)
+
+// checkRTR runs a test client against an RTR server: a Reset Query for the
+// full set followed by a Serial Query, which has to be answered incrementally
+func checkRTR(addr string) error {
+	client, err := rtr.Dial(addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	if _, err := client.Reset(); err != nil {
+		return fmt.Errorf("reset query failed: %v", err)
+	}
+	var ipv4, ipv6 int
+	for _, r := range client.ROAs() {
+		if r.Prefix.Addr().Is4() {
+			ipv4++
+		} else {
+			ipv6++
+		}
+	}
+	log.Printf("Session %d serial %d: %d IPv4 and %d IPv6 ROAs, refresh %ds, retry %ds, expire %ds\n",
+		client.SessionID, client.Serial, ipv4, ipv6, client.Timing.Refresh, client.Timing.Retry, client.Timing.Expire)
+
+	serial := client.Serial
+	update, err := client.Refresh()
+	if err != nil {
+		return fmt.Errorf("serial query failed: %v", err)
+	}
+	if update.Reset {
+		return fmt.Errorf("serial query for the current serial %d was answered with a cache reset", serial)
+	}
+	log.Printf("Serial query from %d to %d: %d announced, %d withdrawn\n",
+		serial, client.Serial, update.Announced, update.Withdrawn)
+	return nil
+}
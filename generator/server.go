@@ -1,103 +1,249 @@
 package main
 
 import (
-	"bytes"
 	"compress/bzip2"
 	"context"
 	"crypto/tls"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net"
 	"net/http"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"runtime"
+	"slices"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/iedon/dn42_map_go/centrality"
 	"github.com/iedon/dn42_map_go/graph"
 	"github.com/iedon/dn42_map_go/mrt"
 	pb "github.com/iedon/dn42_map_go/proto"
 	"github.com/iedon/dn42_map_go/registry"
+	"github.com/iedon/dn42_map_go/roa"
+	"github.com/iedon/dn42_map_go/rtr"
 
 	"google.golang.org/protobuf/proto"
 )
 
+// defaultPostGenerationTimeout applies when post_generation_timeout is not set
+const defaultPostGenerationTimeout = 5 * time.Minute
+
+// defaultRegistryReloadInterval applies when rtr.registry_reload_interval is not set
+const defaultRegistryReloadInterval = 10 * time.Minute
+
 // JSONNode represents a node in JSON format
 type JSONNode struct {
-	ASN             uint32   `json:"asn"`
-	Desc            string   `json:"desc"`
-	Routes          []string `json:"routes"`
-	RoutesMulticast []string `json:"routesMulticast"`
-	Centrality      struct {
-		Degree      float64 `json:"degree"`
-		Betweenness float64 `json:"betweenness"`
-		Closeness   float64 `json:"closeness"`
-		Index       uint32  `json:"index"`
-		Ranking     uint32  `json:"ranking"`
-	} `json:"centrality"`
-	Whois string `json:"whois,omitempty"`
+	ASN                 uint32          `json:"asn"`
+	Desc                string          `json:"desc"`
+	Routes              []string        `json:"routes"`
+	RoutesMulticast     []string        `json:"routesMulticast"`
+	PathFlags           uint32          `json:"pathFlags"`
+	OriginTypes         uint32          `json:"originTypes"`
+	Regions             []uint32        `json:"regions"`
+	Countries           []uint32        `json:"countries"`
+	Communities         []string        `json:"communities"`
+	LargeCommunities    []string        `json:"largeCommunities"`
+	Centrality          JSONCentrality  `json:"centrality"`
+	CentralityIPv4      *JSONCentrality `json:"centralityIpv4,omitempty"`
+	CentralityIPv6      *JSONCentrality `json:"centralityIpv6,omitempty"`
+	CentralityMulticast *JSONCentrality `json:"centralityMulticast,omitempty"`
+	CustomerCone        struct {
+		ASNs              uint32 `json:"asns"`
+		Prefixes          uint32 `json:"prefixes"`
+		PrefixesMulticast uint32 `json:"prefixesMulticast"`
+	} `json:"customerCone"`
+	RouteValidation struct {
+		Valid         uint32 `json:"valid"`
+		InvalidOrigin uint32 `json:"invalidOrigin"`
+		InvalidLength uint32 `json:"invalidLength"`
+		NotFound      uint32 `json:"notFound"`
+	} `json:"routeValidation"`
+	ArticulationPoint  bool     `json:"articulationPoint"`
+	ArticulationCutOff uint32   `json:"articulationCutOff"`
+	CoreNumber         uint32   `json:"coreNumber"`
+	Community          uint32   `json:"community"`
+	Neighbors          []uint32 `json:"neighbors,omitempty"`
+	Upstreams          []uint32 `json:"upstreams,omitempty"`   // Inferred providers
+	Downstreams        []uint32 `json:"downstreams,omitempty"` // Inferred customers
+	Peerings           []uint32 `json:"peerings,omitempty"`    // Inferred peers
+	Whois              string   `json:"whois,omitempty"`
+}
+
+// JSONCentrality represents the centrality metrics of a node in JSON format
+type JSONCentrality struct {
+	Degree         float64 `json:"degree"`
+	Betweenness    float64 `json:"betweenness"`
+	Closeness      float64 `json:"closeness"`
+	Index          uint32  `json:"index"`
+	Ranking        uint32  `json:"ranking"`
+	PageRank       float64 `json:"pagerank"`
+	Eigenvector    float64 `json:"eigenvector"`
+	Harmonic       float64 `json:"harmonic"`
+	InDegree       float64 `json:"inDegree"`
+	OutDegree      float64 `json:"outDegree"`
+	InBetweenness  float64 `json:"inBetweenness"`
+	OutBetweenness float64 `json:"outBetweenness"`
 }
 
 // JSONGraph represents the entire graph in JSON format
 type JSONGraph struct {
 	Metadata struct {
-		Vendor        string `json:"vendor"`
-		GeneratedTime uint64 `json:"generated_timestamp"`
-		DataTime      uint64 `json:"data_timestamp"`
+		Vendor        string           `json:"vendor"`
+		GeneratedTime uint64           `json:"generated_timestamp"`
+		DataTime      uint64           `json:"data_timestamp"`
+		Sources       []JSONDataSource `json:"sources"`
 	} `json:"metadata"`
 	Nodes []JSONNode `json:"nodes"`
 	Links []struct {
-		Source uint32 `json:"source"`
-		Target uint32 `json:"target"`
+		Source        uint32   `json:"source"`
+		Target        uint32   `json:"target"`
+		Af            uint32   `json:"af"`
+		Peers         []uint32 `json:"peers"`
+		PathFlags     uint32   `json:"pathFlags"`
+		LastAnnounced uint64   `json:"lastAnnounced"`
+		LastWithdrawn uint64   `json:"lastWithdrawn"`
+		Latency       uint32   `json:"latency"`
+		Bandwidth     uint32   `json:"bandwidth"`
+		Crypto        uint32   `json:"crypto"`
+		Relationship  string   `json:"relationship"`
+		Confidence    float64  `json:"relationshipConfidence"`
+		PathCount     uint32   `json:"pathCount"`
+		PrefixCount   uint32   `json:"prefixCount"`
+		PeerCount     uint32   `json:"peerCount"`
+		Bridge        bool     `json:"bridge"`
+		BridgeCutOff  uint32   `json:"bridgeCutOff"`
 	} `json:"links"`
+	Peers          []JSONPeer          `json:"peers"`
+	WithdrawnLinks []JSONWithdrawnLink `json:"withdrawnLinks"`
+}
+
+// JSONDataSource represents the status of an MRT source in JSON format
+type JSONDataSource struct {
+	Name          string `json:"name"`
+	Required      bool   `json:"required"`
+	Included      bool   `json:"included"`
+	Error         string `json:"error,omitempty"`
+	DataTimestamp uint64 `json:"data_timestamp"`
+}
+
+// JSONPeer represents a collector feeder peer in JSON format
+type JSONPeer struct {
+	ASN            uint32 `json:"asn"`
+	IP             string `json:"ip"`
+	BGPID          string `json:"bgp_id"`
+	CollectorBGPID string `json:"collector_bgp_id"`
+	ViewName       string `json:"view_name"`
+}
+
+// JSONWithdrawnLink represents an adjacency no longer announced on any path in JSON format
+type JSONWithdrawnLink struct {
+	Source        uint32 `json:"source"`
+	Target        uint32 `json:"target"`
+	LastWithdrawn uint64 `json:"lastWithdrawn"`
 }
 
 // Server
 type Server struct {
-	config       *Config
-	graph        *pb.Graph
-	graphMutex   sync.RWMutex
-	lastModified time.Time
+	config        *Config
+	graph         *pb.Graph
+	index         *graphIndex        // Lookup view of graph, swapped together with it
+	centrality    *centrality.Graph  // Centrality graph of graph for simulations, swapped together with it
+	registry      *registry.Registry // Last loaded registry, refreshed with every generation and registry reload
+	roas          *roa.Set           // ROAs of registry, swapped together with it
+	invalidRoutes []invalidRoute     // Announcements of graph failing origin validation, swapped together with it
+	graphMutex    sync.RWMutex
+	lastModified  time.Time
+	rib           *mrt.RIB // Base RIB for incremental updates, nil unless update URLs are configured
+	ribSources    []*pb.DataSource
+	ribMutex      sync.Mutex
+	jobs          *jobRunner
+	metrics       *Metrics
+	profiles      map[string]centrality.Weights // dn42Index weights by ranking profile
+	rtr           *rtr.Server                   // Serves roas to routers, nil unless enabled
+	registryMutex sync.Mutex                    // Serializes registry reloads
+	simulations   chan struct{}                 // Slots of the simulations running at once
 }
 
 // NewServer creates a new HTTP server
 func NewServer(config *Config) *Server {
-	return &Server{
+	s := &Server{
 		config:       config,
 		lastModified: time.Now(),
-	}
+		metrics:      NewMetrics(),
+		profiles:     rankingProfiles(config),
+		simulations:  make(chan struct{}, simulateMaxRunning),
+	}
+	// Without the API the map is generated once and the process exits,
+	// unless the RTR server is set to keep serving on its own
+	if config.RTR.Enabled && (config.API.Enabled || config.RTR.Standalone) {
+		s.rtr = rtr.NewServer(rtr.Timing{
+			Refresh: config.RTR.RefreshInterval,
+			Retry:   config.RTR.RetryInterval,
+			Expire:  config.RTR.ExpireInterval,
+		})
+	}
+	s.jobs = newJobRunner(s.runJob)
+	s.jobs.finished = s.recordJobMetrics
+	return s
 }
 
-// MRTDownload represents a downloaded MRT file with its source metadata
-type MRTDownload struct {
-	Data        []byte
+// mrtSource describes one configured MRT dump URL
+type mrtSource struct {
+	Name        string
+	URL         string
 	IsMulticast bool
+	Required    bool
 }
 
-func downloadMRTFiles(ctx context.Context, config *Config) ([]MRTDownload, error) {
-	type urlEntry struct {
-		URL         string
-		IsMulticast bool
+// newMRTSource creates a source, which is required unless listed in optional_sources
+func newMRTSource(config *Config, name, url string, isMulticast bool) mrtSource {
+	return mrtSource{
+		Name:        name,
+		URL:         url,
+		IsMulticast: isMulticast,
+		Required:    !slices.Contains(config.MRTCollector.OptionalSources, name),
 	}
-	entries := []urlEntry{
-		{URL: config.MRTCollector.IPv4MRTDumpURL, IsMulticast: false},
-		{URL: config.MRTCollector.IPv6MRTDumpURL, IsMulticast: false},
+}
+
+// mrtSources returns the MRT dump URLs configured for the collector
+func mrtSources(config *Config) []mrtSource {
+	sources := []mrtSource{
+		newMRTSource(config, "ipv4", config.MRTCollector.IPv4MRTDumpURL, false),
+		newMRTSource(config, "ipv6", config.MRTCollector.IPv6MRTDumpURL, false),
 	}
 	if config.MRTCollector.IPv4MulticastMRTDumpURL != "" {
-		entries = append(entries, urlEntry{URL: config.MRTCollector.IPv4MulticastMRTDumpURL, IsMulticast: true})
+		sources = append(sources, newMRTSource(config, "ipv4_multicast", config.MRTCollector.IPv4MulticastMRTDumpURL, true))
 	}
 	if config.MRTCollector.IPv6MulticastMRTDumpURL != "" {
-		entries = append(entries, urlEntry{URL: config.MRTCollector.IPv6MulticastMRTDumpURL, IsMulticast: true})
+		sources = append(sources, newMRTSource(config, "ipv6_multicast", config.MRTCollector.IPv6MulticastMRTDumpURL, true))
 	}
-	results := make([]MRTDownload, 0, len(entries))
-	errCh := make(chan error, len(entries))
-	dataCh := make(chan MRTDownload, len(entries))
+	return sources
+}
 
+// mrtUpdateSources returns the BGP4MP update URLs configured for the collector
+func mrtUpdateSources(config *Config) []mrtSource {
+	var sources []mrtSource
+	if config.MRTCollector.IPv4MRTUpdateURL != "" {
+		sources = append(sources, newMRTSource(config, "ipv4_update", config.MRTCollector.IPv4MRTUpdateURL, false))
+	}
+	if config.MRTCollector.IPv6MRTUpdateURL != "" {
+		sources = append(sources, newMRTSource(config, "ipv6_update", config.MRTCollector.IPv6MRTUpdateURL, false))
+	}
+	if config.MRTCollector.IPv4MulticastMRTUpdateURL != "" {
+		sources = append(sources, newMRTSource(config, "ipv4_multicast_update", config.MRTCollector.IPv4MulticastMRTUpdateURL, true))
+	}
+	if config.MRTCollector.IPv6MulticastMRTUpdateURL != "" {
+		sources = append(sources, newMRTSource(config, "ipv6_multicast_update", config.MRTCollector.IPv6MulticastMRTUpdateURL, true))
+	}
+	return sources
+}
+
+// newMRTClient creates the HTTP client used to fetch MRT dumps
+func newMRTClient(config *Config) *http.Client {
 	// Create a custom HTTP client with custom DNS if specified
 	tr := &http.Transport{
 		TLSClientConfig: &tls.Config{
@@ -125,138 +271,190 @@ func downloadMRTFiles(ctx context.Context, config *Config) ([]MRTDownload, error
 		}
 	}
 
-	client := &http.Client{
+	return &http.Client{
 		Transport: tr,
 		Timeout:   5 * time.Minute,
 	}
+}
 
-	var wg sync.WaitGroup
-	for _, entry := range entries {
-		wg.Add(1)
-		go func(entry urlEntry) {
-			defer wg.Done()
+// fetchMRTFile downloads a single MRT dump and decodes it while the
+// bzip2 stream is being read, without buffering the whole file.
+func fetchMRTFile(ctx context.Context, client *http.Client, config *Config, metrics *Metrics, processor *mrt.Processor, source mrtSource) (*mrt.Result, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", source.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %v", source.URL, err)
+	}
 
-			req, err := http.NewRequestWithContext(ctx, "GET", entry.URL, nil)
-			if err != nil {
-				errCh <- fmt.Errorf("failed to create request for %s: %v", entry.URL, err)
-				return
-			}
+	if config.MRTCollector.Username != "" && config.MRTCollector.Password != "" {
+		req.SetBasicAuth(config.MRTCollector.Username, config.MRTCollector.Password)
+	}
 
-			if config.MRTCollector.Username != "" && config.MRTCollector.Password != "" {
-				req.SetBasicAuth(config.MRTCollector.Username, config.MRTCollector.Password)
-			}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %v", source.URL, err)
+	}
+	defer resp.Body.Close()
 
-			resp, err := client.Do(req)
-			if err != nil {
-				errCh <- fmt.Errorf("failed to download %s: %v", entry.URL, err)
-				return
-			}
-			defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d for %s", resp.StatusCode, source.URL)
+	}
 
-			if resp.StatusCode != http.StatusOK {
-				errCh <- fmt.Errorf("unexpected status code %d for %s", resp.StatusCode, entry.URL)
-				return
-			}
+	// Decompress using bzip2 and decode records as they are produced
+	body := &countingReader{r: resp.Body}
+	result, err := processor.ProcessReader(bzip2.NewReader(body), source.IsMulticast)
+	metrics.Add(metricMRTBytes, float64(body.n), "source", source.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process %s: %v", source.URL, err)
+	}
+	metrics.Add(metricMRTEntries, float64(len(result.ASPaths)+len(result.Updates)), "source", source.Name)
+	return result, nil
+}
 
-			// Decompress using bzip2 with streaming
-			bzReader := bzip2.NewReader(resp.Body)
-
-			// Use a buffer to read data in chunks
-			var buffer bytes.Buffer
-			chunk := make([]byte, 32*1024) // 32KB chunks
-
-			for {
-				n, err := bzReader.Read(chunk)
-				if n > 0 {
-					buffer.Write(chunk[:n])
-				}
-				if err == io.EOF {
-					break
-				}
-				if err != nil {
-					errCh <- fmt.Errorf("failed to decompress %s: %v", entry.URL, err)
-					return
-				}
-			}
+// processMRTFiles concurrently downloads and decodes the given MRT files.
+// Failures of optional sources are only recorded in the returned source
+// statuses, a failing required source fails the whole run.
+func processMRTFiles(ctx context.Context, config *Config, metrics *Metrics, sources []mrtSource) ([]*mrt.Result, []*pb.DataSource, error) {
+	client := newMRTClient(config)
+	processor := mrt.NewProcessor()
 
-			// Send the decompressed data with source tag
-			dataCh <- MRTDownload{Data: buffer.Bytes(), IsMulticast: entry.IsMulticast}
+	results := make([]*mrt.Result, len(sources))
+	errs := make([]error, len(sources))
 
-			// Clear the buffer to help GC
-			buffer.Reset()
-		}(entry)
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func(i int, source mrtSource) {
+			defer wg.Done()
+			results[i], errs[i] = fetchMRTFile(ctx, client, config, metrics, processor, source)
+		}(i, source)
 	}
+	wg.Wait()
 
-	// Wait for all downloads to complete
-	go func() {
-		wg.Wait()
-		close(dataCh)
-		close(errCh)
-	}()
+	var included []*mrt.Result
+	statuses := make([]*pb.DataSource, 0, len(sources))
+	for i, source := range sources {
+		status := &pb.DataSource{
+			Name:     source.Name,
+			Required: source.Required,
+		}
+		statuses = append(statuses, status)
 
-	// Collect results and errors
-	for data := range dataCh {
-		results = append(results, data)
-	}
+		if errs[i] != nil {
+			if source.Required {
+				return nil, nil, errs[i]
+			}
+			log.Printf("Optional MRT source %s failed, continuing without it: %v\n", source.Name, errs[i])
+			status.Error = errs[i].Error()
+			continue
+		}
 
-	for err := range errCh {
-		if err != nil {
-			return nil, err
+		status.Included = true
+		if results[i].Metadata != nil {
+			status.DataTimestamp = results[i].Metadata.Timestamp
 		}
+		included = append(included, results[i])
 	}
 
-	return results, nil
+	if len(included) == 0 && len(sources) > 0 {
+		return nil, nil, fmt.Errorf("none of the %d MRT sources could be processed", len(sources))
+	}
+
+	return included, statuses, nil
+}
+
+// mergeMRTResults merges the results of all processed MRT files
+func mergeMRTResults(mrtResults []*mrt.Result) *mrt.Result {
+	results := make(chan *mrt.Result, len(mrtResults))
+	for _, result := range mrtResults {
+		results <- result
+	}
+	close(results)
+	return mrt.MergeResults(results)
 }
 
-// generateMap generates map data
-func (s *Server) generateMap() {
+// runJob runs a generation job of the requested kind
+func (s *Server) runJob(j *job) error {
+	if j.kind == jobUpdate {
+		return s.updateMap(j)
+	}
+	return s.generateMap(j)
+}
+
+// generateMap generates map data from full MRT dumps
+func (s *Server) generateMap(j *job) error {
 	log.Printf("Map generation started at %s\n", time.Now().UTC().Format(http.TimeFormat))
 
 	ctx := context.Background()
 	start := time.Now()
 
-	// Concurrent download MRT files
-	mrtData, err := downloadMRTFiles(ctx, s.config)
+	// Concurrent download and process MRT files
+	j.phase(phaseDownloading)
+	mrtResults, sources, err := processMRTFiles(ctx, s.config, s.metrics, mrtSources(s.config))
 	if err != nil {
-		log.Printf("failed to download MRT files: %v\n", err)
-		return
+		return fmt.Errorf("failed to process MRT files: %v", err)
 	}
 
-	// Concurrent process MRT data
-	processor := mrt.NewProcessor()
-	results := make(chan *mrt.Result, len(mrtData))
-	var wg sync.WaitGroup
+	j.phase(phaseParsing)
+	merged := mergeMRTResults(mrtResults)
 
-	for _, dl := range mrtData {
-		wg.Add(1)
-		go func(dl MRTDownload) {
-			defer wg.Done()
-			result, err := processor.Process(dl.Data, dl.IsMulticast)
-			if err != nil {
-				log.Printf("Error processing MRT data: %v\n", err)
-				return
-			}
-			results <- result
-		}(dl)
+	// Keep the dump as base RIB if incremental updates are configured
+	if len(mrtUpdateSources(s.config)) > 0 {
+		s.ribMutex.Lock()
+		s.rib = mrt.NewRIB(merged)
+		s.ribSources = sources
+		s.ribMutex.Unlock()
+	}
+
+	return s.publishMap(j, merged, sources, start, true)
+}
+
+// updateMap applies the configured BGP4MP update files on top of the base
+// RIB of the last full generation and regenerates the map from it
+func (s *Server) updateMap(j *job) error {
+	s.ribMutex.Lock()
+	rib := s.rib
+	baseSources := s.ribSources
+	s.ribMutex.Unlock()
+
+	if rib == nil {
+		log.Println("No base RIB available for incremental update, running full generation instead")
+		return s.generateMap(j)
 	}
 
-	// Wait for all processing to complete and close the channel
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
+	log.Printf("Map update started at %s\n", time.Now().UTC().Format(http.TimeFormat))
 
-	// Merge results
-	merged := mrt.MergeResults(results)
+	ctx := context.Background()
+	start := time.Now()
+
+	j.phase(phaseDownloading)
+	mrtResults, updateSources, err := processMRTFiles(ctx, s.config, s.metrics, mrtUpdateSources(s.config))
+	if err != nil {
+		return fmt.Errorf("failed to process MRT update files: %v", err)
+	}
 
+	j.phase(phaseParsing)
+	merged := mergeMRTResults(mrtResults)
+	applied := rib.Apply(merged.Updates)
+	log.Printf("Applied %d of %d updates to the base RIB\n", applied, len(merged.Updates))
+
+	sources := append(slices.Clone(baseSources), updateSources...)
+	return s.publishMap(j, rib.Result(), sources, start, false)
+}
+
+// publishMap builds the map from processed MRT data, writes it to the output
+// file and swaps it in for the API. sources records the MRT sources that
+// went into or are missing from the map, full whether it comes from full
+// dumps rather than updates.
+func (s *Server) publishMap(j *job, merged *mrt.Result, sources []*pb.DataSource, start time.Time, full bool) error {
 	// Check if we should skip generation on empty data
 	if s.config.DoNotGenerateOnEmpty && len(merged.ASPaths) == 0 && len(merged.Advertises) == 0 {
 		log.Println("No paths or routes found in MRT data and do_not_generate_on_empty is enabled. Skipping generation.")
-		return
+		return nil
 	}
 
 	// Concurrent get ASN descriptions
-	reg := registry.NewRegistry(s.config.RegistryPath)
+	j.phase(phaseRegistry)
+	reg, roas := s.refreshRegistry()
 	uniqueASNs := make(map[uint32]struct{})
 	for _, asp := range merged.ASPaths {
 		for _, asn := range asp.Path {
@@ -266,51 +464,145 @@ func (s *Server) generateMap() {
 	asnDescriptions := reg.GetDescriptions(uniqueASNs)
 
 	// Build Graph protobuf
-	graphPb := graph.BuildGraph(merged, asnDescriptions)
+	j.phase(phaseCentrality)
+	graphPb, centralityGraph := graph.BuildGraph(merged, asnDescriptions, s.profiles[defaultRankingProfile])
+	graphPb.Metadata.Sources = sources
+	invalidRoutes := validateRoutes(graphPb, roas)
+
+	// Refuse to replace the last known good map with a degenerate one
+	j.phase(phaseWriting)
+	s.graphMutex.RLock()
+	prev := s.graph
+	s.graphMutex.RUnlock()
+	if prev == nil {
+		prev, _ = loadGraph(s.config.OutputFile)
+	}
+	if err := checkGraphSanity(prev, graphPb, s.config.MinNodeRatio, s.config.MinLinkRatio); err != nil {
+		return fmt.Errorf("sanity check failed, keeping previous map: %v", err)
+	}
 
 	// Serialize
 	data, err := proto.Marshal(graphPb)
 	if err != nil {
-		log.Printf("failed to marshal graph: %v\n", err)
-		return
+		return fmt.Errorf("failed to marshal graph: %v", err)
 	}
 
-	// Save to file
-	if err := os.WriteFile(s.config.OutputFile, data, 0644); err != nil {
-		log.Printf("failed to write output file: %v\n", err)
-		return
+	// Keep previous maps and atomically replace the output file. Only full
+	// generations rotate, frequent updates would push out older maps.
+	if full {
+		if err := rotateOutputs(s.config.OutputFile, s.config.OutputRetention); err != nil {
+			log.Printf("failed to keep previous output files: %v\n", err)
+		}
+	}
+	if err := writeFileAtomic(s.config.OutputFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
 	}
 
 	// Update in-memory data
+	index := newGraphIndex(graphPb)
 	s.graphMutex.Lock()
 	s.graph = graphPb
+	s.index = index
+	s.centrality = centralityGraph
+	s.invalidRoutes = invalidRoutes
 	s.lastModified = time.Now()
 	s.graphMutex.Unlock()
+	s.recordGraphMetrics(graphPb)
 
 	// Execute post-generation command if specified
 	if s.config.PostGenerationCommand != "" {
+		j.phase(phasePostCommand)
+		timeout := defaultPostGenerationTimeout
+		if s.config.PostGenerationTimeout > 0 {
+			timeout = time.Duration(s.config.PostGenerationTimeout) * time.Second
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
 		var cmd *exec.Cmd
 		if runtime.GOOS == "windows" {
-			cmd = exec.Command("cmd", "/c", s.config.PostGenerationCommand)
+			cmd = exec.CommandContext(ctx, "cmd", "/c", s.config.PostGenerationCommand)
 		} else {
-			cmd = exec.Command("sh", "-c", s.config.PostGenerationCommand)
+			cmd = exec.CommandContext(ctx, "sh", "-c", s.config.PostGenerationCommand)
 		}
 
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
+		cmd.WaitDelay = time.Second // Don't wait on children of a killed shell holding its output open
 
-		if err := cmd.Start(); err != nil {
-			log.Printf("Error starting post generation command: %v\n", err)
-		} else {
-			go func() {
-				if err := cmd.Wait(); err != nil {
-					log.Printf("Post generation command exited with error: %v\n", err)
-				}
-			}()
+		err := cmd.Run()
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("killed after timeout of %v", timeout)
+		}
+		exitCode := cmd.ProcessState.ExitCode() // -1 if the command could not be started
+		s.metrics.Set(metricPostCommandExitCode, float64(exitCode))
+		s.metrics.Add(metricPostCommandRuns, 1, "exit_code", strconv.Itoa(exitCode))
+		if err != nil {
+			log.Printf("Post generation command exited with error: %v\n", err)
+			j.postCommandFailed(err)
 		}
 	}
 
 	log.Printf("Map generation completed in %v\n", time.Since(start))
+	return nil
+}
+
+// refreshRegistry loads the registry and swaps it in with its ROAs, which
+// are exported and served to routers if they changed. If it cannot be
+// loaded, the previous registry and ROAs stay in use and are returned, or an
+// empty registry and ROA set for the map if none was loaded yet.
+func (s *Server) refreshRegistry() (*registry.Registry, *roa.Set) {
+	s.registryMutex.Lock()
+	defer s.registryMutex.Unlock()
+
+	s.graphMutex.RLock()
+	prevRegistry, prevROAs := s.registry, s.roas
+	s.graphMutex.RUnlock()
+
+	reg, err := registry.Load(s.config.RegistryPath)
+	if err != nil {
+		if prevRegistry == nil {
+			log.Printf("Failed to load registry, using ASNs as descriptions: %v\n", err)
+			reg = registry.New()
+			return reg, roa.Build(reg)
+		}
+		log.Printf("Failed to load registry, keeping the previous registry and ROA set: %v\n", err)
+		return prevRegistry, prevROAs
+	}
+	if errs := reg.Errors(); len(errs) > 0 {
+		log.Printf("Skipped malformed registry data in %d places, first: %v\n", len(errs), errs[0])
+	}
+
+	// Unchanged ROAs keep their generation time for If-Modified-Since
+	roas := roa.Build(reg)
+	changed := prevROAs == nil || !slices.Equal(prevROAs.ROAs(), roas.ROAs())
+	if changed {
+		writeROAOutputs(s.config.ROA, roas)
+	} else {
+		roas = prevROAs
+	}
+
+	s.graphMutex.Lock()
+	s.registry = reg
+	s.roas = roas
+	s.graphMutex.Unlock()
+
+	if changed && s.rtr != nil {
+		s.rtr.Update(roas)
+	}
+	return reg, roas
+}
+
+// reloadRegistry refreshes the registry and its ROAs right away and then
+// every interval, independently of map generations that may fail on MRT
+// sources
+func (s *Server) reloadRegistry(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.refreshRegistry()
+		<-ticker.C
+	}
 }
 
 // checkIfModified checks if the response should be modified based on If-Modified-Since header
@@ -339,16 +631,29 @@ func (s *Server) sendJSONResponse(w http.ResponseWriter) error {
 		return err
 	}
 
+	sources := make([]JSONDataSource, 0, len(s.graph.Metadata.Sources))
+	for _, source := range s.graph.Metadata.Sources {
+		sources = append(sources, JSONDataSource{
+			Name:          source.Name,
+			Required:      source.Required,
+			Included:      source.Included,
+			Error:         source.Error,
+			DataTimestamp: source.DataTimestamp,
+		})
+	}
+
 	metadata := struct {
-		Vendor             string `json:"vendor"`
-		GeneratedTimestamp uint64 `json:"generated_timestamp"`
-		DataTimestamp      uint64 `json:"data_timestamp"`
-		Version            uint32 `json:"version"`
+		Vendor             string           `json:"vendor"`
+		GeneratedTimestamp uint64           `json:"generated_timestamp"`
+		DataTimestamp      uint64           `json:"data_timestamp"`
+		Version            uint32           `json:"version"`
+		Sources            []JSONDataSource `json:"sources"`
 	}{
 		Vendor:             s.graph.Metadata.Vendor,
 		GeneratedTimestamp: s.graph.Metadata.GeneratedTimestamp,
 		DataTimestamp:      s.graph.Metadata.DataTimestamp,
 		Version:            s.graph.Metadata.Version,
+		Sources:            sources,
 	}
 	if err := enc.Encode(metadata); err != nil {
 		return err
@@ -382,13 +687,83 @@ func (s *Server) sendJSONResponse(w http.ResponseWriter) error {
 			}
 		}
 		if err := enc.Encode(struct {
-			Source uint32 `json:"source"`
-			Target uint32 `json:"target"`
-			Af     uint32 `json:"af"`
+			Source        uint32   `json:"source"`
+			Target        uint32   `json:"target"`
+			Af            uint32   `json:"af"`
+			Peers         []uint32 `json:"peers"`
+			PathFlags     uint32   `json:"pathFlags"`
+			LastAnnounced uint64   `json:"lastAnnounced"`
+			LastWithdrawn uint64   `json:"lastWithdrawn"`
+			Latency       uint32   `json:"latency"`
+			Bandwidth     uint32   `json:"bandwidth"`
+			Crypto        uint32   `json:"crypto"`
+			Relationship  string   `json:"relationship"`
+			Confidence    float64  `json:"relationshipConfidence"`
+			PathCount     uint32   `json:"pathCount"`
+			PrefixCount   uint32   `json:"prefixCount"`
+			PeerCount     uint32   `json:"peerCount"`
+			Bridge        bool     `json:"bridge"`
+			BridgeCutOff  uint32   `json:"bridgeCutOff"`
 		}{
-			Source: link.Source,
-			Target: link.Target,
-			Af:     link.Af,
+			Source:        link.Source,
+			Target:        link.Target,
+			Af:            link.Af,
+			Peers:         link.Peers,
+			PathFlags:     link.PathFlags,
+			LastAnnounced: link.LastAnnounced,
+			LastWithdrawn: link.LastWithdrawn,
+			Latency:       link.Latency,
+			Bandwidth:     link.Bandwidth,
+			Crypto:        link.Crypto,
+			Relationship:  relationshipName(link.Relationship),
+			Confidence:    link.RelationshipConfidence,
+			PathCount:     link.PathCount,
+			PrefixCount:   link.PrefixCount,
+			PeerCount:     uint32(len(link.Peers)),
+			Bridge:        link.Bridge,
+			BridgeCutOff:  link.BridgeCutOff,
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Peers array
+	if _, err := w.Write([]byte(`],"peers":[`)); err != nil {
+		return err
+	}
+
+	for i, peer := range s.graph.Peers {
+		if i > 0 {
+			if _, err := w.Write([]byte{','}); err != nil {
+				return err
+			}
+		}
+		if err := enc.Encode(JSONPeer{
+			ASN:            peer.Asn,
+			IP:             peer.Ip,
+			BGPID:          peer.BgpId,
+			CollectorBGPID: peer.CollectorBgpId,
+			ViewName:       peer.ViewName,
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Withdrawn links array
+	if _, err := w.Write([]byte(`],"withdrawnLinks":[`)); err != nil {
+		return err
+	}
+
+	for i, link := range s.graph.WithdrawnLinks {
+		if i > 0 {
+			if _, err := w.Write([]byte{','}); err != nil {
+				return err
+			}
+		}
+		if err := enc.Encode(JSONWithdrawnLink{
+			Source:        link.Source,
+			Target:        link.Target,
+			LastWithdrawn: link.LastWithdrawn,
 		}); err != nil {
 			return err
 		}
@@ -413,16 +788,6 @@ func (s *Server) sendProtobufResponse(w http.ResponseWriter) error {
 	return err
 }
 
-// readWhois reads the whois information for a given ASN
-func readWhois(registryPath string, asn uint32) string {
-	whoisPath := filepath.Join(registryPath, "data", "aut-num", fmt.Sprintf("AS%d", asn))
-	data, err := os.ReadFile(whoisPath)
-	if err != nil {
-		return ""
-	}
-	return string(data)
-}
-
 // formatRoute converts a protobuf Route to a string representation
 func formatRoute(route *pb.Route) string {
 	switch ip := route.Ip.(type) {
@@ -444,13 +809,66 @@ func formatRoute(route *pb.Route) string {
 	return ""
 }
 
+// relationshipName returns the short JSON name of an inferred link relationship
+func relationshipName(r pb.Relationship) string {
+	switch r {
+	case pb.Relationship_RELATIONSHIP_PROVIDER_TO_CUSTOMER:
+		return "p2c"
+	case pb.Relationship_RELATIONSHIP_CUSTOMER_TO_PROVIDER:
+		return "c2p"
+	case pb.Relationship_RELATIONSHIP_PEER:
+		return "p2p"
+	}
+	return "unknown"
+}
+
+// convertCentralityToJSON converts a protobuf Centrality to JSONCentrality,
+// an unset centrality converts to nil
+func convertCentralityToJSON(c *pb.Centrality) *JSONCentrality {
+	if c == nil {
+		return nil
+	}
+	return &JSONCentrality{
+		Degree:         c.Degree,
+		Betweenness:    c.Betweenness,
+		Closeness:      c.Closeness,
+		Index:          c.Index,
+		Ranking:        c.Ranking,
+		PageRank:       c.Pagerank,
+		Eigenvector:    c.Eigenvector,
+		Harmonic:       c.Harmonic,
+		InDegree:       c.InDegree,
+		OutDegree:      c.OutDegree,
+		InBetweenness:  c.InBetweenness,
+		OutBetweenness: c.OutBetweenness,
+	}
+}
+
 // convertNodeToJSON converts a protobuf Node to JSONNode
 func (s *Server) convertNodeToJSON(node *pb.Node, includeWhois bool) JSONNode {
 	jsonNode := JSONNode{
-		ASN:             node.Asn,
-		Desc:            node.Desc,
-		Routes:          make([]string, len(node.Routes)),
-		RoutesMulticast: make([]string, len(node.RoutesMulticast)),
+		ASN:                node.Asn,
+		Desc:               node.Desc,
+		Routes:             make([]string, len(node.Routes)),
+		RoutesMulticast:    make([]string, len(node.RoutesMulticast)),
+		PathFlags:          node.PathFlags,
+		OriginTypes:        node.OriginTypes,
+		Regions:            node.Regions,
+		Countries:          node.Countries,
+		Communities:        make([]string, len(node.Communities)),
+		LargeCommunities:   make([]string, len(node.LargeCommunities)),
+		ArticulationPoint:  node.ArticulationPoint,
+		ArticulationCutOff: node.ArticulationCutOff,
+		CoreNumber:         node.CoreNumber,
+		Community:          node.Community,
+	}
+
+	for j, c := range node.Communities {
+		jsonNode.Communities[j] = fmt.Sprintf("%d:%d", c>>16, c&0xFFFF)
+	}
+
+	for j, lc := range node.LargeCommunities {
+		jsonNode.LargeCommunities[j] = fmt.Sprintf("%d:%d:%d", lc.GlobalAdmin, lc.LocalData1, lc.LocalData2)
 	}
 
 	for j, route := range node.Routes {
@@ -461,14 +879,32 @@ func (s *Server) convertNodeToJSON(node *pb.Node, includeWhois bool) JSONNode {
 		jsonNode.RoutesMulticast[j] = formatRoute(route)
 	}
 
-	jsonNode.Centrality.Degree = node.Centrality.Degree
-	jsonNode.Centrality.Betweenness = node.Centrality.Betweenness
-	jsonNode.Centrality.Closeness = node.Centrality.Closeness
-	jsonNode.Centrality.Index = node.Centrality.Index
-	jsonNode.Centrality.Ranking = node.Centrality.Ranking
+	if c := convertCentralityToJSON(node.Centrality); c != nil {
+		jsonNode.Centrality = *c
+	}
+	jsonNode.CentralityIPv4 = convertCentralityToJSON(node.CentralityIpv4)
+	jsonNode.CentralityIPv6 = convertCentralityToJSON(node.CentralityIpv6)
+	jsonNode.CentralityMulticast = convertCentralityToJSON(node.CentralityMulticast)
+
+	if node.CustomerCone != nil {
+		jsonNode.CustomerCone.ASNs = node.CustomerCone.Asns
+		jsonNode.CustomerCone.Prefixes = node.CustomerCone.Prefixes
+		jsonNode.CustomerCone.PrefixesMulticast = node.CustomerCone.PrefixesMulticast
+	}
+
+	if v := node.RouteValidation; v != nil {
+		jsonNode.RouteValidation.Valid = v.Valid
+		jsonNode.RouteValidation.InvalidOrigin = v.InvalidOrigin
+		jsonNode.RouteValidation.InvalidLength = v.InvalidLength
+		jsonNode.RouteValidation.NotFound = v.NotFound
+	}
 
 	if includeWhois {
-		jsonNode.Whois = readWhois(s.config.RegistryPath, node.Asn)
+		jsonNode.Neighbors = s.index.Neighbors(node.Asn)
+		jsonNode.Upstreams = s.index.Upstreams(node.Asn)
+		jsonNode.Downstreams = s.index.Downstreams(node.Asn)
+		jsonNode.Peerings = s.index.Peerings(node.Asn)
+		jsonNode.Whois = s.registry.Whois(node.Asn)
 	}
 
 	return jsonNode
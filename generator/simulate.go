@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+
+	"github.com/iedon/dn42_map_go/centrality"
+)
+
+// Simulation limits
+const (
+	simulateTopN        = 10      // Default number of betweenness shifts and ranked nodes returned
+	simulateMaxBodySize = 1 << 20 // Maximum size of a simulation request body
+	simulateMaxRunning  = 2       // Maximum number of simulations running at once
+)
+
+// simulateRequest is the body of a /simulate request
+type simulateRequest struct {
+	ASNs  []uint32 `json:"asns"`
+	Links []struct {
+		Source uint32 `json:"source"`
+		Target uint32 `json:"target"`
+	} `json:"links"`
+	Top int `json:"top"`
+}
+
+// simulateShift is the betweenness change of a node in a simulation
+type simulateShift struct {
+	ASN    uint32  `json:"asn"`
+	Desc   string  `json:"desc"`
+	Before float64 `json:"before"`
+	After  float64 `json:"after"`
+	Change float64 `json:"change"`
+}
+
+// simulateRank is a node of the ranking after a simulation
+type simulateRank struct {
+	Rank  uint32 `json:"rank"`
+	ASN   uint32 `json:"asn"`
+	Desc  string `json:"desc"`
+	Index uint32 `json:"index"`
+}
+
+// simulateResponse is the result of a /simulate request
+type simulateResponse struct {
+	RemovedASNs       int `json:"removedAsns"`
+	RemovedLinks      int `json:"removedLinks"`
+	LostReachability  int `json:"lostReachability"` // Remaining ASes that can no longer reach all ASes they reached before
+	AveragePathLength struct {
+		Before float64 `json:"before"`
+		After  float64 `json:"after"`
+	} `json:"averagePathLength"`
+	BetweennessShifts []simulateShift `json:"betweennessShifts"`
+	Ranking           []simulateRank  `json:"ranking"`
+}
+
+// handleSimulate handles POST /simulate requests, recalculating the
+// centrality of the current map without the given ASes and links
+func (s *Server) handleSimulate(w http.ResponseWriter, r *http.Request) {
+	// Validate authentication token
+	if !s.authorize(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Every simulation recalculates the centrality of the whole graph
+	select {
+	case s.simulations <- struct{}{}:
+		defer func() { <-s.simulations }()
+	default:
+		http.Error(w, "Too many simulations running", http.StatusTooManyRequests)
+		return
+	}
+
+	var req simulateRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, simulateMaxBodySize)).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	slices.Sort(req.ASNs)
+	req.ASNs = slices.Compact(req.ASNs)
+	if len(req.ASNs) == 0 && len(req.Links) == 0 {
+		http.Error(w, "Nothing to remove, set asns or links", http.StatusBadRequest)
+		return
+	}
+
+	// Snapshots are never modified once swapped in, so they can be used
+	// after releasing the lock
+	s.graphMutex.RLock()
+	before, index := s.centrality, s.index
+	s.graphMutex.RUnlock()
+	if before == nil {
+		http.Error(w, "Map data not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	for _, asn := range req.ASNs {
+		if before.GetNode(asn) == nil {
+			http.Error(w, fmt.Sprintf("ASN %d not found", asn), http.StatusBadRequest)
+			return
+		}
+	}
+	links := make([][2]uint32, len(req.Links))
+	for i, link := range req.Links {
+		if _, found := slices.BinarySearch(index.Neighbors(link.Source), link.Target); !found {
+			http.Error(w, fmt.Sprintf("Link %d-%d not found", link.Source, link.Target), http.StatusBadRequest)
+			return
+		}
+		links[i] = [2]uint32{link.Source, link.Target}
+	}
+
+	after := before.Without(req.ASNs, links)
+	after.CalculateCentrality()
+
+	top := req.Top
+	if top <= 0 {
+		top = simulateTopN
+	}
+	top = min(top, len(after.Nodes))
+	resp := simulateResponse{
+		RemovedASNs:       len(req.ASNs),
+		RemovedLinks:      len(req.Links),
+		LostReachability:  lostReachability(before, after, req.ASNs),
+		BetweennessShifts: betweennessShifts(before, after, index, top),
+		Ranking:           make([]simulateRank, 0, top),
+	}
+	resp.AveragePathLength.Before = before.AveragePathLength()
+	resp.AveragePathLength.After = after.AveragePathLength()
+
+	// Nodes of after are sorted by their new ranking
+	for _, node := range after.Nodes[:top] {
+		resp.Ranking = append(resp.Ranking, simulateRank{
+			Rank:  node.Ranking,
+			ASN:   node.ASN,
+			Desc:  index.Node(node.ASN).Desc,
+			Index: node.Index,
+		})
+	}
+
+	setHeaders(w, "application/json", nil)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// lostReachability counts the remaining ASes that reach fewer ASes than
+// before, beyond the removed ASes of their own component
+func lostReachability(before, after *centrality.Graph, removed []uint32) int {
+	removedInComponent := make(map[uint32]uint32)
+	for _, asn := range removed {
+		removedInComponent[before.GetNode(asn).Component]++
+	}
+
+	lost := 0
+	for _, node := range after.Nodes {
+		prev := before.GetNode(node.ASN)
+		if node.Reachable < prev.Reachable-removedInComponent[prev.Component] {
+			lost++
+		}
+	}
+	return lost
+}
+
+// betweennessShifts returns the top remaining nodes by absolute change of
+// their betweenness
+func betweennessShifts(before, after *centrality.Graph, index *graphIndex, top int) []simulateShift {
+	shifts := make([]simulateShift, 0, len(after.Nodes))
+	for _, node := range after.Nodes {
+		prev := before.GetNode(node.ASN)
+		shifts = append(shifts, simulateShift{
+			ASN:    node.ASN,
+			Desc:   index.Node(node.ASN).Desc,
+			Before: prev.Betweenness,
+			After:  node.Betweenness,
+			Change: node.Betweenness - prev.Betweenness,
+		})
+	}
+	sort.SliceStable(shifts, func(i, j int) bool {
+		return math.Abs(shifts[i].Change) > math.Abs(shifts[j].Change)
+	})
+	return shifts[:min(top, len(shifts))]
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/netip"
+
+	pb "github.com/iedon/dn42_map_go/proto"
+	"github.com/iedon/dn42_map_go/roa"
+)
+
+// jsonROA represents a ROA in JSON format
+type jsonROA struct {
+	Prefix    string `json:"prefix"`
+	MaxLength int    `json:"maxLength"`
+	Origin    uint32 `json:"origin"`
+}
+
+// invalidRoute is an announcement failing origin validation
+type invalidRoute struct {
+	Prefix string    `json:"prefix"`
+	Origin uint32    `json:"origin"`
+	Desc   string    `json:"desc"`
+	State  string    `json:"state"`
+	ROAs   []jsonROA `json:"roas"` // ROAs covering the prefix
+}
+
+// validateRoutes validates the distinct unicast routes of every node against the
+// ROAs derived from the registry, stores the counts per state on the nodes and
+// returns the invalid announcements
+func validateRoutes(graph *pb.Graph, set *roa.Set) []invalidRoute {
+	invalid := make([]invalidRoute, 0)
+	for _, node := range graph.Nodes {
+		counts := &pb.RouteValidation{}
+		seen := make(map[netip.Prefix]bool, len(node.Routes))
+		for _, route := range node.Routes {
+			prefix, ok := routePrefix(route)
+			if !ok || seen[prefix] {
+				continue
+			}
+			seen[prefix] = true
+
+			validity, covering := set.Validate(prefix, node.Asn)
+			switch validity {
+			case roa.ValidityValid:
+				counts.Valid++
+				continue
+			case roa.ValidityNotFound:
+				counts.NotFound++
+				continue
+			case roa.ValidityInvalidOrigin:
+				counts.InvalidOrigin++
+			case roa.ValidityInvalidLength:
+				counts.InvalidLength++
+			}
+
+			entry := invalidRoute{
+				Prefix: prefix.String(),
+				Origin: node.Asn,
+				Desc:   node.Desc,
+				State:  validity.String(),
+				ROAs:   make([]jsonROA, len(covering)),
+			}
+			for i, r := range covering {
+				entry.ROAs[i] = jsonROA{Prefix: r.Prefix.String(), MaxLength: r.MaxLength, Origin: r.Origin}
+			}
+			invalid = append(invalid, entry)
+		}
+		node.RouteValidation = counts
+	}
+	return invalid
+}
+
+// handleValidation handles /validation requests
+func (s *Server) handleValidation(w http.ResponseWriter, r *http.Request) {
+	s.graphMutex.RLock()
+	defer s.graphMutex.RUnlock()
+
+	if s.graph == nil {
+		http.Error(w, "Map data not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	response := struct {
+		LastModified string `json:"last_modified"`
+		Summary      struct {
+			Valid         uint32 `json:"valid"`
+			InvalidOrigin uint32 `json:"invalidOrigin"`
+			InvalidLength uint32 `json:"invalidLength"`
+			NotFound      uint32 `json:"notFound"`
+		} `json:"summary"`
+		Invalid []invalidRoute `json:"invalid"`
+	}{
+		LastModified: s.lastModified.UTC().Format(http.TimeFormat),
+		Invalid:      s.invalidRoutes,
+	}
+	for _, node := range s.graph.Nodes {
+		response.Summary.Valid += node.RouteValidation.GetValid()
+		response.Summary.InvalidOrigin += node.RouteValidation.GetInvalidOrigin()
+		response.Summary.InvalidLength += node.RouteValidation.GetInvalidLength()
+		response.Summary.NotFound += node.RouteValidation.GetNotFound()
+	}
+
+	setHeaders(w, "application/json", &s.lastModified)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}